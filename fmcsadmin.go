@@ -19,38 +19,62 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math/big"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"reflect"
 	"regexp"
 	"runtime"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	jwt "github.com/golang-jwt/jwt/v5"
-	"github.com/mattn/go-scan"
 	"github.com/olekukonko/tablewriter"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
 )
 
 var version string
 
 type cli struct {
+	inStream             io.Reader
 	outStream, errStream io.Writer
+	// reader is shared by the shell and the confirmation prompts so that the input read ahead is not lost
+	reader *bufio.Reader
+	// command and exitCodeMode are used to map the result of the last command to the process exit status
+	command      string
+	exitCodeMode string
+	// failure is the mapped exit status of a failure that the FileMaker error code does not tell apart
+	failure int
+	// session is the session of the shell running the command
+	session *shellSession
 }
 
 type output struct {
@@ -64,6 +88,183 @@ type output struct {
 	} `json:"messages"`
 }
 
+type apiMessage struct {
+	Code string `json:"code"`
+	Text string `json:"text"`
+}
+
+// apiID is an identifier returned either as a string or as a number.
+type apiID string
+
+func (id *apiID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = apiID(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = apiID(n.String())
+
+	return nil
+}
+
+type clientsResponse struct {
+	Response struct {
+		Clients []clientRecord `json:"clients"`
+	} `json:"response"`
+	Messages []apiMessage `json:"messages"`
+}
+
+type clientRecord struct {
+	ID              apiID             `json:"id"`
+	Status          string            `json:"status"`
+	UserName        string            `json:"userName"`
+	ComputerName    string            `json:"computerName"`
+	ExtPriv         string            `json:"extpriv"`
+	IPAddress       string            `json:"ipaddress"`
+	MACAddress      string            `json:"macaddress"`
+	ConnectTime     string            `json:"connectTime"`
+	ConnectDuration string            `json:"connectDuration"`
+	AppVersion      string            `json:"appVersion"`
+	AppLanguage     string            `json:"appLanguage"`
+	GuestFiles      []guestFileRecord `json:"guestFiles"`
+}
+
+type guestFileRecord struct {
+	ID          apiID  `json:"id"`
+	FileName    string `json:"filename"`
+	AccountName string `json:"accountName"`
+	PrivsetName string `json:"privsetName"`
+}
+
+type databasesResponse struct {
+	Response struct {
+		TotalDBCount int              `json:"totalDBCount"`
+		Databases    []databaseRecord `json:"databases"`
+	} `json:"response"`
+	Messages []apiMessage `json:"messages"`
+}
+
+type databaseRecord struct {
+	ID                   apiID    `json:"id"`
+	FileName             string   `json:"filename"`
+	Folder               string   `json:"folder"`
+	Status               string   `json:"status"`
+	Clients              int      `json:"clients"`
+	Size                 int64    `json:"size"`
+	EnabledExtPrivileges []string `json:"enabledExtPrivileges"`
+	IsEncrypted          bool     `json:"isEncrypted"`
+	DecryptHint          string   `json:"decryptHint"`
+}
+
+type schedulesResponse struct {
+	Response struct {
+		Schedules []scheduleRecord `json:"schedules"`
+		Schedule  scheduleRecord   `json:"schedule"`
+	} `json:"response"`
+	Messages []apiMessage `json:"messages"`
+}
+
+// scheduleRecord is a schedule. Only one of the task types is present and
+// lastRun or nextRun is absent when the schedule has never run or won't run.
+type scheduleRecord struct {
+	ID         apiID   `json:"id"`
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Enabled    bool    `json:"enabled"`
+	LastRun    *string `json:"lastRun"`
+	NextRun    *string `json:"nextRun"`
+	BackupType *struct {
+		ResourceType string `json:"resourceType"`
+		BackupTarget string `json:"backupTarget"`
+	} `json:"backupType"`
+	FilemakerScriptType *struct {
+		Resource string `json:"resource"`
+	} `json:"filemakerScriptType"`
+	MessageType *struct {
+		ResourceType string `json:"resourceType"`
+	} `json:"messageType"`
+	ScriptSequenceType *struct {
+		Resource string `json:"resource"`
+	} `json:"scriptSequenceType"`
+	SystemScriptType *struct {
+		OsScript string `json:"osScript"`
+	} `json:"systemScriptType"`
+	VerifyType *struct {
+		ResourceType string `json:"resourceType"`
+	} `json:"verifyType"`
+}
+
+func (s scheduleRecord) taskType() string {
+	if s.BackupType != nil && s.BackupType.ResourceType != "" {
+		return "Backup"
+	} else if s.FilemakerScriptType != nil && s.FilemakerScriptType.Resource != "" {
+		return "FileMaker Script"
+	} else if s.MessageType != nil && s.MessageType.ResourceType != "" {
+		return "Message"
+	} else if s.ScriptSequenceType != nil && s.ScriptSequenceType.Resource != "" {
+		return "Script Sequence"
+	} else if s.SystemScriptType != nil && s.SystemScriptType.OsScript != "" {
+		return "System Script"
+	} else if s.VerifyType != nil && s.VerifyType.ResourceType != "" {
+		return "Verify"
+	}
+
+	return ""
+}
+
+type pluginsResponse struct {
+	Response struct {
+		Plugins []pluginRecord `json:"plugins"`
+	} `json:"response"`
+	Messages []apiMessage `json:"messages"`
+}
+
+type pluginRecord struct {
+	ID         apiID  `json:"id"`
+	PluginName string `json:"pluginName"`
+	FileName   string `json:"filename"`
+	Enabled    bool   `json:"enabled"`
+}
+
+type metadataResponse struct {
+	Response struct {
+		ServerVersion string `json:"ServerVersion"`
+	} `json:"response"`
+	Messages []apiMessage `json:"messages"`
+}
+
+// configResponse covers the configuration endpoints of the server. Settings
+// the server doesn't return are left nil.
+type configResponse struct {
+	Response struct {
+		CacheSize                   int    `json:"cacheSize"`
+		MaxFiles                    int    `json:"maxFiles"`
+		MaxProConnections           int    `json:"maxProConnections"`
+		MaxPSOS                     int    `json:"maxPSOS"`
+		StartupRestorationEnabled   *bool  `json:"startupRestorationEnabled"`
+		OnlyOpenLastOpenedDatabases *bool  `json:"onlyOpenLastOpenedDatabases"`
+		AuthenticatedStream         *int   `json:"authenticatedStream"`
+		RequireSecureDB             *bool  `json:"requireSecureDB"`
+		ParallelBackupEnabled       *bool  `json:"parallelBackupEnabled"`
+		BlockNewUsers               *bool  `json:"blockNewUsers"`
+		EnableHTTPSTunneling        *bool  `json:"enableHTTPSTunneling"`
+		PersistentCache             bool   `json:"persistentCache"`
+		PersistentCacheSync         bool   `json:"persistentCacheSync"`
+		DatabaseServerAutoRestart   bool   `json:"databaseServerAutoRestart"`
+		Enabled                     *bool  `json:"enabled"`
+		CharacterEncoding           string `json:"characterEncoding"`
+		ErrorMessageLanguage        string `json:"errorMessageLanguage"`
+		DataPreValidation           bool   `json:"dataPreValidation"`
+		UseFileMakerPhp             bool   `json:"useFileMakerPhp"`
+	} `json:"response"`
+	Messages []apiMessage `json:"messages"`
+}
+
 type generalOldConfigInfo struct {
 	CacheSize                 int  `json:"cacheSize"`
 	MaxFiles                  int  `json:"maxFiles"`
@@ -125,6 +326,10 @@ type xmlConfigInfo struct {
 	Enabled bool `json:"enabled"`
 }
 
+type connectorConfigInfo struct {
+	Enabled bool `json:"enabled"`
+}
+
 type dbInfo struct {
 	Status  string `json:"status"`
 	Key     string `json:"key"`
@@ -196,37 +401,201 @@ type params struct {
 	intermediateCertificates    string
 	printRefreshToken           bool
 	identityFile                string
+	credentialHelper            string
 }
 
 type commandOptions struct {
-	helpFlag       bool
-	versionFlag    bool
-	yesFlag        bool
-	statsFlag      bool
-	forceFlag      bool
-	saveKeyFlag    bool
-	fqdn           string
-	hostname       string
-	username       string
-	password       string
-	key            string
-	message        string
-	keyFile        string
-	keyFilePass    string
-	intermediateCA string
-	clientID       int
-	graceTime      int
-	identityFile   string
+	helpFlag               bool
+	versionFlag            bool
+	yesFlag                bool
+	statsFlag              bool
+	forceFlag              bool
+	saveKeyFlag            bool
+	fqdn                   string
+	hostname               string
+	username               string
+	password               string
+	key                    string
+	message                string
+	keyFile                string
+	keyFilePass            string
+	intermediateCA         string
+	clientID               int
+	graceTime              int
+	identityFile           string
+	passwordFile           string
+	passwordStdin          bool
+	credentialHelper       string
+	fileFilter             string
+	userFilter             string
+	appVersionFilter       string
+	ipFilter               string
+	sortKey                string
+	where                  string
+	at                     string
+	repeat                 string
+	until                  string
+	scheduleID             int
+	dryRun                 bool
+	humanFlag              bool
+	timeZone               string
+	clientsWarning         int
+	clientsCritical        int
+	certWarning            int
+	certCritical           int
+	policyFile             string
+	maintenanceFlag        bool
+	debugFlag              bool
+	traceFlag              bool
+	logFile                string
+	retries                int
+	retryMaxWait           string
+	retryNonIdempotentFlag bool
+	exitCodeMode           string
+}
+
+var commandTree = map[string][]string{
+	"cancel":      {"backup"},
+	"certificate": {"create", "delete", "import"},
+	"check":       {},
+	"close":       {},
+	"completion":  {"bash", "fish", "powershell", "zsh"},
+	"credential":  {"erase", "list", "store"},
+	"delete":      {"schedule"},
+	"disable":     {"plugin", "schedule"},
+	"disconnect":  {"client", "clients"},
+	"enable":      {"plugin", "schedule"},
+	"get":         {"backuptime", "connectorconfig", "cwpconfig", "serverconfig", "serverprefs"},
+	"help":        {"commands", "error", "options"},
+	"info":        {},
+	"lint":        {},
+	"list":        {"backups", "clients", "files", "plugins", "schedules"},
+	"open":        {},
+	"pause":       {},
+	"remove":      {},
+	"restart":     {"server"},
+	"restore":     {},
+	"resume":      {},
+	"run":         {"schedule"},
+	"send":        {},
+	"set":         {"connectorconfig", "cwpconfig", "serverconfig", "serverprefs"},
+	"shell":       {},
+	"start":       {"server"},
+	"status":      {"client", "file", "server"},
+	"stop":        {"server"},
+}
+
+var configNames = map[string][]string{
+	"connectorconfig": {"enabledataapi", "enableodata", "enablewebdirect"},
+	"cwpconfig":       {"enablephp", "enablexml", "encoding", "locale", "prevalidation", "usefmphp"},
+	"serverconfig":    {"cachesize", "hostedfiles", "proconnections", "scriptsessions", "securefilesonly"},
+	"serverprefs":     {"allowpsos", "authenticatedstream", "blocknewusersenabled", "cachesize", "databaseserverautorestart", "enablehttpprotocolnetwork", "maxfiles", "maxguests", "onlyopenlastopeneddatabases", "parallelbackupenabled", "persistcacheenabled", "requiresecuredb", "startuprestorationenabled", "syncpersistcache"},
 }
 
+var allowedOptions = []string{"-h", "-v", "-y", "-s", "-u", "-p", "-m", "-f", "-c", "-t", "-i", "--help", "--version", "--yes", "--stats", "--fqdn", "--host", "--username", "--password", "--key", "--message", "--force", "--client", "--gracetime", "--savekey", "--keyfile", "--KeyFile", "--keyfilepass", "--KeyFilePass", "--intermediateca", "--intermediateCA", "--password-file", "--password-stdin", "--credential-helper", "--file", "--user", "--app-version", "--ip", "--sort", "--where", "--at", "--repeat", "--until", "--schedule", "--dry-run", "--human", "--tz", "--clients-warning", "--clients-critical", "--cert-warning", "--cert-critical", "--policy", "--maintenance", "--debug", "--trace", "--log-file", "--retries", "--retry-max-wait", "--retry-non-idempotent", "--exit-code-mode"}
+
+// options that take a value
+var valueOptions = []string{"-u", "-p", "-m", "-c", "-t", "-i", "--fqdn", "--host", "--username", "--password", "--key", "--message", "--client", "--gracetime", "--keyfile", "--keyfilepass", "--intermediateca", "--password-file", "--credential-helper", "--file", "--user", "--app-version", "--ip", "--sort", "--where", "--at", "--repeat", "--until", "--schedule", "--tz", "--clients-warning", "--clients-critical", "--cert-warning", "--cert-critical", "--policy", "--log-file", "--retries", "--retry-max-wait", "--exit-code-mode"}
+
 func main() {
-	cli := &cli{outStream: os.Stdout, errStream: os.Stderr}
-	os.Exit(cli.Run(os.Args))
+	cli := &cli{inStream: os.Stdin, outStream: os.Stdout, errStream: os.Stderr}
+	exitStatus := cli.Run(os.Args)
+	os.Exit(getProcessExitStatus(exitStatus, cli.command, cli.exitCodeMode, cli.failure))
+}
+
+// Process exit statuses in the mapped exit code mode. The FileMaker error code
+// is printed as "Error: N (...)" in both modes.
+const (
+	exitStatusError          = 1
+	exitStatusUsageError     = 2
+	exitStatusAuthentication = 3
+	exitStatusNotFound       = 4
+	exitStatusUnreachable    = 5
+	exitStatusNotSupported   = 6
+	exitStatusConflict       = 7
+	exitStatusFileError      = 8
+	exitStatusHTTPError      = 9
+	exitStatusTimeout        = 10
+	exitStatusPartialFailure = 11
+)
+
+// exitStatusCategories maps the FileMaker error codes to the process exit statuses.
+var exitStatusCategories = map[int]int{
+	23:    exitStatusNotFound,
+	248:   exitStatusUsageError,
+	249:   exitStatusUsageError,
+	958:   exitStatusUsageError,
+	960:   exitStatusUsageError,
+	1705:  exitStatusUsageError,
+	1706:  exitStatusUsageError,
+	1707:  exitStatusUsageError,
+	1708:  exitStatusUsageError,
+	1710:  exitStatusUsageError,
+	10001: exitStatusUsageError,
+	11000: exitStatusUsageError,
+	11001: exitStatusUsageError,
+	25004: exitStatusUsageError,
+	9:     exitStatusAuthentication,
+	212:   exitStatusAuthentication,
+	214:   exitStatusAuthentication,
+	1702:  exitStatusAuthentication,
+	25006: exitStatusAuthentication,
+	100:   exitStatusNotFound,
+	1700:  exitStatusNotFound,
+	10007: exitStatusNotFound,
+	10600: exitStatusNotFound,
+	10904: exitStatusNotFound,
+	10906: exitStatusNotFound,
+	11005: exitStatusNotFound,
+	20405: exitStatusNotFound,
+	1701:  exitStatusUnreachable,
+	10502: exitStatusUnreachable,
+	3:     exitStatusNotSupported,
+	4:     exitStatusNotSupported,
+	21:    exitStatusNotSupported,
+	1704:  exitStatusNotSupported,
+	1713:  exitStatusNotSupported,
+	12:    exitStatusConflict,
+	956:   exitStatusConflict,
+	1709:  exitStatusConflict,
+	1712:  exitStatusConflict,
+	10006: exitStatusConflict,
+	10611: exitStatusConflict,
+	20406: exitStatusConflict,
+	20402: exitStatusFileError,
+	20408: exitStatusFileError,
+	20501: exitStatusFileError,
+}
+
+// getProcessExitStatus returns the process exit status for the result of the command.
+// The exit statuses of the CHECK and LINT commands (0 to 3) are not mapped.
+// failure is the exit status of a failure that the FileMaker error code does not
+// tell apart, such as an HTTP error, a timeout or a partial failure, or 0.
+func getProcessExitStatus(exitStatus int, command string, exitCodeMode string, failure int) int {
+	if exitStatus == 0 || exitCodeMode != "mapped" {
+		return exitStatus
+	}
+	if (command == "check" || command == "lint") && exitStatus > 0 && exitStatus <= 3 {
+		return exitStatus
+	}
+	if failure != 0 {
+		return failure
+	}
+	if status, ok := exitStatusCategories[exitStatus]; ok {
+		return status
+	}
+
+	return exitStatusError
 }
 
 func (c *cli) Run(args []string) int {
 	var exitStatus int
 
+	if len(args) > 1 && args[1] == "__complete" {
+		// called back from the shell completion scripts
+		return outputCompletionCandidates(c, args[2:])
+	}
+
 	token := ""
 	exitStatus = 0
 	helpFlag := false
@@ -248,6 +617,35 @@ func (c *cli) Run(args []string) int {
 	keyFilePass := ""
 	intermediateCA := ""
 	identityFile := ""
+	passwordFile := ""
+	passwordStdin := false
+	credentialHelper := ""
+	fileFilter := ""
+	userFilter := ""
+	appVersionFilter := ""
+	ipFilter := ""
+	sortKey := ""
+	where := ""
+	at := ""
+	repeat := ""
+	until := ""
+	scheduleID := 0
+	dryRun := false
+	humanFlag := false
+	timeZone := ""
+	clientsWarning := -1
+	clientsCritical := -1
+	certWarning := -1
+	certCritical := -1
+	policyFile := ""
+	maintenanceFlag := false
+	debugFlag := false
+	traceFlag := false
+	logFile := ""
+	retries := 0
+	retryMaxWait := ""
+	retryNonIdempotentFlag := false
+	exitCodeMode := ""
 
 	commandOptions := commandOptions{}
 	commandOptions.helpFlag = false
@@ -268,9 +666,43 @@ func (c *cli) Run(args []string) int {
 	commandOptions.clientID = -1
 	commandOptions.graceTime = 90
 	commandOptions.identityFile = ""
+	commandOptions.passwordFile = ""
+	commandOptions.passwordStdin = false
+	commandOptions.credentialHelper = ""
+	commandOptions.fileFilter = ""
+	commandOptions.userFilter = ""
+	commandOptions.appVersionFilter = ""
+	commandOptions.ipFilter = ""
+	commandOptions.sortKey = ""
+	commandOptions.where = ""
+	commandOptions.at = ""
+	commandOptions.repeat = ""
+	commandOptions.until = ""
+	commandOptions.scheduleID = 0
+	commandOptions.dryRun = false
+	commandOptions.humanFlag = false
+	commandOptions.timeZone = ""
+	commandOptions.clientsWarning = -1
+	commandOptions.clientsCritical = -1
+	commandOptions.certWarning = -1
+	commandOptions.certCritical = -1
+	commandOptions.policyFile = ""
+	commandOptions.maintenanceFlag = false
+	commandOptions.debugFlag = false
+	commandOptions.traceFlag = false
+	commandOptions.logFile = ""
+	commandOptions.retries = 0
+	commandOptions.retryMaxWait = ""
+	commandOptions.retryNonIdempotentFlag = false
+	commandOptions.exitCodeMode = ""
 
 	// detect an invalid command
 	cmdArgs, cFlags, err := getFlags(args, commandOptions)
+	c.command = ""
+	if len(cmdArgs) > 0 {
+		c.command = strings.ToLower(cmdArgs[0])
+	}
+	c.exitCodeMode = strings.ToLower(cFlags.exitCodeMode)
 	if err != nil {
 		fmt.Fprintln(c.outStream, flag.ErrHelp)
 		exitStatus = outputInvalidCommandErrorMessage(c)
@@ -284,12 +716,16 @@ func (c *cli) Run(args []string) int {
 			// Allow option (ex.: "fmcsadmin get backuptime -1")
 			invalidOption = false
 		} else {
-			allowedOptions := []string{"-h", "-v", "-y", "-s", "-u", "-p", "-m", "-f", "-c", "-t", "-i", "--help", "--version", "--yes", "--stats", "--fqdn", "--host", "--username", "--password", "--key", "--message", "--force", "--client", "--gracetime", "--savekey", "--keyfile", "--KeyFile", "--keyfilepass", "--KeyFilePass", "--intermediateca", "--intermediateCA"}
 			for j := 0; j < len(allowedOptions); j++ {
 				if string([]rune(args[i])[:1]) == "-" {
 					invalidOption = true
+					option := args[i]
+					if strings.HasPrefix(option, "--") && strings.Contains(option, "=") {
+						// "--option=value"
+						option = option[:strings.Index(option, "=")]
+					}
 					for _, v := range allowedOptions {
-						if strings.ToLower(args[i]) == v {
+						if strings.ToLower(option) == v {
 							if v == "--keyfilepass" {
 								keyFilePassOption = true
 							}
@@ -321,6 +757,42 @@ func (c *cli) Run(args []string) int {
 	keyFilePass = cFlags.keyFilePass
 	intermediateCA = cFlags.intermediateCA
 	identityFile = cFlags.identityFile
+	passwordFile = cFlags.passwordFile
+	passwordStdin = cFlags.passwordStdin
+	credentialHelper = cFlags.credentialHelper
+	fileFilter = cFlags.fileFilter
+	userFilter = cFlags.userFilter
+	appVersionFilter = cFlags.appVersionFilter
+	ipFilter = cFlags.ipFilter
+	sortKey = cFlags.sortKey
+	where = cFlags.where
+	at = cFlags.at
+	repeat = cFlags.repeat
+	until = cFlags.until
+	scheduleID = cFlags.scheduleID
+	dryRun = cFlags.dryRun
+	// human-readable output is the default on a terminal unless "--human=false" is given
+	humanFlag = cFlags.humanFlag || (isTerminalWriter(c.outStream) && !isOptionDisabled(args, "--human"))
+	timeZone = cFlags.timeZone
+	clientsWarning = cFlags.clientsWarning
+	clientsCritical = cFlags.clientsCritical
+	certWarning = cFlags.certWarning
+	if certWarning == -1 {
+		certWarning = 30
+	}
+	certCritical = cFlags.certCritical
+	if certCritical == -1 {
+		certCritical = 7
+	}
+	policyFile = cFlags.policyFile
+	maintenanceFlag = cFlags.maintenanceFlag
+	debugFlag = cFlags.debugFlag
+	traceFlag = cFlags.traceFlag
+	logFile = cFlags.logFile
+	retries = cFlags.retries
+	retryMaxWait = cFlags.retryMaxWait
+	retryNonIdempotentFlag = cFlags.retryNonIdempotentFlag
+	exitCodeMode = cFlags.exitCodeMode
 
 	fqdn = cFlags.fqdn
 	hostname = cFlags.hostname
@@ -330,10 +802,76 @@ func (c *cli) Run(args []string) int {
 	baseURI := getBaseURI(fqdn)
 	u, _ := url.Parse(baseURI)
 
-	usingCloud := false
-	if regexp.MustCompile(`https://(.*)\.account\.filemaker-cloud\.com/`).Match([]byte(baseURI)) {
-		// Not Supported
-		usingCloud = true
+	usingCloud := isCloudURI(baseURI)
+
+	lastAPIError = nil
+	c.failure = 0
+	serverVersionCache = map[string]string{}
+	defer func() { serverVersionCache = nil }()
+	if apiLogger == nil {
+		logLevel, logFileName, err := parseLogSetting(os.Getenv("FMCSADMIN_LOG"))
+		if err != nil {
+			fmt.Fprintln(c.errStream, "fmcsadmin: FMCSADMIN_LOG: "+err.Error())
+		}
+		if debugFlag {
+			logLevel = "debug"
+		}
+		if traceFlag {
+			logLevel = "trace"
+		}
+		if len(logFile) > 0 {
+			logFileName = logFile
+		}
+		if len(logLevel) > 0 {
+			var logWriter io.Writer = c.errStream
+			if len(logFileName) > 0 {
+				f, err := os.OpenFile(logFileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+				if err != nil {
+					fmt.Fprintln(c.outStream, "fmcsadmin: "+err.Error())
+					return 20402
+				}
+				defer f.Close()
+				logWriter = f
+			}
+			apiLogger = &trafficLogger{out: logWriter, trace: logLevel == "trace"}
+			defer func() { apiLogger = nil }()
+		}
+	}
+
+	switch strings.ToLower(exitCodeMode) {
+	case "", "mapped", "legacy":
+	default:
+		fmt.Fprintln(c.outStream, "Invalid parameter for option: --exit-code-mode")
+		return 10001
+	}
+
+	if retries < 0 {
+		fmt.Fprintln(c.outStream, "Invalid parameter for option: --retries")
+		return 10001
+	}
+	maxWait, err := parseRetryMaxWait(retryMaxWait)
+	if err != nil {
+		fmt.Fprintln(c.outStream, "Invalid parameter for option: --retry-max-wait")
+		return 10001
+	}
+	if retries > 0 {
+		previousRetryPolicy := apiRetryPolicy
+		apiRetryPolicy = retryPolicy{retries: retries, maxWait: maxWait, nonIdempotent: retryNonIdempotentFlag}
+		defer func() { apiRetryPolicy = previousRetryPolicy }()
+	}
+
+	if len(timeZone) > 0 && !isValidTimeZone(timeZone) {
+		fmt.Fprintln(c.outStream, "Invalid parameter for option: --tz")
+		return 10001
+	}
+	output := outputOptions{human: humanFlag, timeZone: timeZone}
+
+	if len(password) == 0 && (len(passwordFile) > 0 || passwordStdin) {
+		password, exitStatus = readPassword(c, passwordFile, passwordStdin)
+		if exitStatus != 0 {
+			outputErrorMessage(exitStatus, c)
+			return exitStatus
+		}
 	}
 
 	retry := 3
@@ -359,10 +897,9 @@ func (c *cli) Run(args []string) int {
 						}
 
 						if running {
-							token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 							if token != "" && exitStatus == 0 && err == nil {
-								version := getServerVersion(u.String(), token)
-								if !usingCloud && version >= 19.5 {
+								if requireCapability(c, baseURI, token, "cancel backup", usingCloud) {
 									u.Path = path.Join(getAPIBasePath(), "server", "cancelbackup")
 									exitStatus, _, err = sendRequest("POST", u.String(), token, params{command: "cancel backup"})
 									if err == nil {
@@ -373,7 +910,7 @@ func (c *cli) Run(args []string) int {
 								} else {
 									exitStatus = outputInvalidCommandErrorMessage(c)
 								}
-								logout(baseURI, token)
+								c.logout(baseURI, token)
 							} else if detectHostUnreachable(exitStatus) {
 								exitStatus = 10502
 							}
@@ -402,10 +939,9 @@ func (c *cli) Run(args []string) int {
 						}
 
 						if running {
-							token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 							if token != "" && exitStatus == 0 && err == nil {
-								version := getServerVersion(u.String(), token)
-								if version >= 19.2 {
+								if requireCapability(c, baseURI, token, "certificate", usingCloud) {
 									if len(cmdArgs) < 3 {
 										fmt.Fprintln(c.outStream, "Certificate subject is not specified.")
 										exitStatus = 10001
@@ -433,7 +969,7 @@ func (c *cli) Run(args []string) int {
 								} else {
 									exitStatus = outputInvalidCommandErrorMessage(c)
 								}
-								logout(baseURI, token)
+								c.logout(baseURI, token)
 							} else if detectHostUnreachable(exitStatus) {
 								exitStatus = 10502
 							}
@@ -445,17 +981,14 @@ func (c *cli) Run(args []string) int {
 						if yesFlag {
 							res = "y"
 						} else {
-							r := bufio.NewReader(os.Stdin)
 							fmt.Fprint(c.outStream, "fmcsadmin: really import certificate? (y, n) (Warning: server needs to be restarted) ")
-							input, _ := r.ReadString('\n')
+							input, _ := c.input().ReadString('\n')
 							res = strings.ToLower(strings.TrimSpace(input))
 						}
 						if res == "y" {
-							token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 							if token != "" && exitStatus == 0 && err == nil {
-								u.Path = path.Join(getAPIBasePath(), "server", "metadata")
-								version := getServerVersion(u.String(), token)
-								if version >= 19.2 {
+								if requireCapability(c, baseURI, token, "certificate", usingCloud) {
 									if len(cmdArgs[2:]) > 0 {
 										keyFileData := []byte("")
 
@@ -629,7 +1162,7 @@ func (c *cli) Run(args []string) int {
 								} else {
 									exitStatus = outputInvalidCommandErrorMessage(c)
 								}
-								logout(baseURI, token)
+								c.logout(baseURI, token)
 							} else if detectHostUnreachable(exitStatus) {
 								exitStatus = 10502
 							}
@@ -639,17 +1172,14 @@ func (c *cli) Run(args []string) int {
 						if yesFlag {
 							res = "y"
 						} else {
-							r := bufio.NewReader(os.Stdin)
 							fmt.Fprint(c.outStream, "fmcsadmin: really delete certificate? (y, n) (Warning: server needs to be restarted) ")
-							input, _ := r.ReadString('\n')
+							input, _ := c.input().ReadString('\n')
 							res = strings.ToLower(strings.TrimSpace(input))
 						}
 						if res == "y" {
-							token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 							if token != "" && exitStatus == 0 && err == nil {
-								u.Path = path.Join(getAPIBasePath(), "server", "metadata")
-								version := getServerVersion(u.String(), token)
-								if version >= 19.2 {
+								if requireCapability(c, baseURI, token, "certificate", usingCloud) {
 									u.Path = path.Join(getAPIBasePath(), "server", "certificate", "delete")
 									exitStatus, _, err = sendRequest("DELETE", u.String(), token, params{})
 									if err != nil {
@@ -661,7 +1191,7 @@ func (c *cli) Run(args []string) int {
 								} else {
 									exitStatus = outputInvalidCommandErrorMessage(c)
 								}
-								logout(baseURI, token)
+								c.logout(baseURI, token)
 							} else if detectHostUnreachable(exitStatus) {
 								exitStatus = 10502
 							}
@@ -673,18 +1203,29 @@ func (c *cli) Run(args []string) int {
 					exitStatus = outputInvalidCommandErrorMessage(c)
 				}
 			}
+		case "check":
+			// print a single line and exit with 0-3 in the same way as Nagios plugins
+			token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+			if token != "" && exitStatus == 0 && err == nil {
+				exitStatus = checkServer(c, baseURI, token, cmdArgs[1:], checkThresholds{clientsWarning: clientsWarning, clientsCritical: clientsCritical, certWarning: certWarning, certCritical: certCritical}, usingCloud)
+				c.logout(baseURI, token)
+			} else if detectHostUnreachable(exitStatus) {
+				exitStatus = (&checkResult{state: checkCritical, problems: []string{"Admin API is not responding"}}).output(c)
+			} else {
+				exitStatus = (&checkResult{state: checkUnknown, problems: []string{"could not log in (error " + strconv.Itoa(exitStatus) + ")"}}).output(c)
+			}
+			return exitStatus
 		case "close":
 			res := ""
 			if yesFlag {
 				res = "y"
 			} else {
-				r := bufio.NewReader(os.Stdin)
 				fmt.Fprint(c.outStream, "fmcsadmin: really close database(s)? (y, n) ")
-				input, _ := r.ReadString('\n')
+				input, _ := c.input().ReadString('\n')
 				res = strings.ToLower(strings.TrimSpace(input))
 			}
 			if res == "y" {
-				token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+				token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 				if token != "" && exitStatus == 0 && err == nil {
 					u.Path = path.Join(getAPIBasePath(), "databases")
 					args = []string{""}
@@ -697,22 +1238,97 @@ func (c *cli) Run(args []string) int {
 							fmt.Fprintln(c.outStream, "File Closing: "+nameList[i])
 						}
 						connectedClients := getClients(u.String(), token, args)
+						var result batchResult
 						for i := 0; i < len(idList); i++ {
 							u.Path = path.Join(getAPIBasePath(), "databases", strconv.Itoa(idList[i]))
 							exitStatus, _, err = sendRequest("PATCH", u.String(), token, params{command: "close", messageText: message, force: forceFlag})
-							if exitStatus == 0 && err == nil && len(connectedClients) == 0 {
+							if result.add(exitStatus, err) && len(connectedClients) == 0 {
 								// Don't output this message when the clients connected to the specified databases are existing
 								fmt.Fprintln(c.outStream, "File Closed: "+nameList[i])
 							}
 						}
+						exitStatus = result.getExitStatus(c)
 					} else {
 						exitStatus = 10904
 					}
-					logout(baseURI, token)
+					c.logout(baseURI, token)
 				} else if detectHostUnreachable(exitStatus) {
 					exitStatus = 10502
 				}
 			}
+		case "completion":
+			if len(cmdArgs[1:]) > 0 {
+				exitStatus = outputCompletionScript(c, cmdArgs[1])
+			} else {
+				exitStatus = outputInvalidCommandErrorMessage(c)
+			}
+		case "credential":
+			if len(cmdArgs[1:]) > 0 {
+				u, _ := url.Parse(baseURI)
+				filePath := getCredentialFilePath()
+				switch strings.ToLower(cmdArgs[1]) {
+				case "store":
+					username, password, _ = getUsernameAndPassword(username, password, 1)
+					store, err := loadCredentialStore(filePath)
+					passphrase := ""
+					if errors.Is(err, os.ErrNotExist) {
+						store, err = newCredentialStore()
+						passphrase = getMasterPassphrase(true)
+					} else if err == nil {
+						passphrase = getMasterPassphrase(false)
+					}
+					if err == nil && len(passphrase) > 0 {
+						err = store.set(u.Host, username, password, passphrase)
+						if err == nil {
+							err = saveCredentialStore(filePath, store)
+						}
+					} else if err == nil {
+						err = errors.New("invalid master passphrase")
+					}
+					if err == nil {
+						fmt.Fprintln(c.outStream, "Credential Stored: "+u.Host)
+					} else {
+						fmt.Fprintln(c.outStream, "fmcsadmin: Could not store the credential: "+err.Error())
+						exitStatus = 9
+					}
+				case "erase":
+					store, err := loadCredentialStore(filePath)
+					found := false
+					if err == nil {
+						if _, found = store.Entries[u.Host]; found {
+							delete(store.Entries, u.Host)
+							err = saveCredentialStore(filePath, store)
+						}
+					}
+					if err == nil && found {
+						fmt.Fprintln(c.outStream, "Credential Erased: "+u.Host)
+					} else if err == nil || errors.Is(err, os.ErrNotExist) {
+						exitStatus = 20405
+					} else {
+						fmt.Fprintln(c.outStream, "fmcsadmin: Could not erase the credential: "+err.Error())
+						exitStatus = 9
+					}
+				case "list":
+					store, err := loadCredentialStore(filePath)
+					if err == nil {
+						hosts := []string{}
+						for host := range store.Entries {
+							hosts = append(hosts, host)
+						}
+						sort.Strings(hosts)
+						for _, host := range hosts {
+							fmt.Fprintln(c.outStream, host)
+						}
+					} else if !errors.Is(err, os.ErrNotExist) {
+						fmt.Fprintln(c.outStream, "fmcsadmin: Could not read the credential file: "+err.Error())
+						exitStatus = 9
+					}
+				default:
+					exitStatus = outputInvalidCommandErrorMessage(c)
+				}
+			} else {
+				exitStatus = outputInvalidCommandErrorMessage(c)
+			}
 		case "delete":
 			if len(cmdArgs[1:]) > 0 {
 				switch strings.ToLower(cmdArgs[1]) {
@@ -721,13 +1337,12 @@ func (c *cli) Run(args []string) int {
 					if yesFlag {
 						res = "y"
 					} else {
-						r := bufio.NewReader(os.Stdin)
 						fmt.Fprint(c.outStream, "fmcsadmin: really delete a schedule? (y, n) ")
-						input, _ := r.ReadString('\n')
+						input, _ := c.input().ReadString('\n')
 						res = strings.ToLower(strings.TrimSpace(input))
 					}
 					if res == "y" {
-						token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+						token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 						if token != "" && exitStatus == 0 && err == nil {
 							id := 0
 							if len(cmdArgs) >= 3 {
@@ -753,7 +1368,7 @@ func (c *cli) Run(args []string) int {
 							} else {
 								exitStatus = 10600
 							}
-							logout(baseURI, token)
+							c.logout(baseURI, token)
 						} else if detectHostUnreachable(exitStatus) {
 							exitStatus = 10502
 						}
@@ -770,13 +1385,12 @@ func (c *cli) Run(args []string) int {
 					if yesFlag {
 						res = "y"
 					} else {
-						r := bufio.NewReader(os.Stdin)
 						fmt.Fprint(c.outStream, "fmcsadmin: really disable schedule(s)? (y, n) ")
-						input, _ := r.ReadString('\n')
+						input, _ := c.input().ReadString('\n')
 						res = strings.ToLower(strings.TrimSpace(input))
 					}
 					if res == "y" {
-						token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+						token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 						if token != "" && exitStatus == 0 && err == nil {
 							id := 0
 							if len(cmdArgs) >= 3 {
@@ -790,12 +1404,36 @@ func (c *cli) Run(args []string) int {
 								exitStatus, _, err = sendRequest("PATCH", u.String(), token, params{command: "disable"})
 								if exitStatus == 0 && err == nil {
 									u.Path = path.Join(getAPIBasePath(), "schedules")
-									exitStatus = listSchedules(u.String(), token, id)
+									exitStatus = listSchedules(c, u.String(), token, id, output.resolve(baseURI, usingCloud))
 								}
 							} else {
 								exitStatus = 10600
 							}
-							logout(baseURI, token)
+							c.logout(baseURI, token)
+						} else if detectHostUnreachable(exitStatus) {
+							exitStatus = 10502
+						}
+					}
+				case "plugin":
+					res := ""
+					if yesFlag {
+						res = "y"
+					} else {
+						fmt.Fprint(c.outStream, "fmcsadmin: really disable plug-in(s)? (y, n) ")
+						input, _ := c.input().ReadString('\n')
+						res = strings.ToLower(strings.TrimSpace(input))
+					}
+					if res == "y" {
+						token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+						if token != "" && exitStatus == 0 && err == nil {
+							if !requireCapability(c, baseURI, token, "disable plugin", usingCloud) {
+								exitStatus = 21
+							} else if len(cmdArgs[2:]) > 0 {
+								exitStatus = changePluginStatus(c, u, token, cmdArgs[2:], false)
+							} else {
+								exitStatus = 10007
+							}
+							c.logout(baseURI, token)
 						} else if detectHostUnreachable(exitStatus) {
 							exitStatus = 10502
 						}
@@ -814,13 +1452,12 @@ func (c *cli) Run(args []string) int {
 					if yesFlag {
 						res = "y"
 					} else {
-						r := bufio.NewReader(os.Stdin)
 						fmt.Fprint(c.outStream, "fmcsadmin: really disconnect client(s)? (y, n) ")
-						input, _ := r.ReadString('\n')
+						input, _ := c.input().ReadString('\n')
 						res = strings.ToLower(strings.TrimSpace(input))
 					}
 					if res == "y" {
-						token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+						token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 						if token != "" && exitStatus == 0 && err == nil {
 							id := 0
 							if len(cmdArgs) >= 3 {
@@ -863,11 +1500,49 @@ func (c *cli) Run(args []string) int {
 									fmt.Fprintln(c.outStream, "Client(s) being disconnected.")
 								}
 							}
-							logout(baseURI, token)
+							c.logout(baseURI, token)
 						} else if detectHostUnreachable(exitStatus) {
 							exitStatus = 10502
 						}
 					}
+				case "clients":
+					conditions, err := parseClientConditions(where)
+					if err != nil {
+						fmt.Fprintln(c.outStream, "Invalid parameter for option: --where ("+err.Error()+")")
+						exitStatus = 10001
+						break
+					}
+					token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+					if token != "" && exitStatus == 0 && err == nil {
+						u.Path = path.Join(getAPIBasePath(), "clients")
+						var clients []clientInfo
+						clients, exitStatus = getClientInfo(c.outStream, u.String(), token)
+						if exitStatus == 0 {
+							clients = selectClients(clients, conditions)
+							if len(clients) == 0 {
+								fmt.Fprintln(c.outStream, "No clients match the specified conditions.")
+							} else {
+								outputClientSummary(c, clients)
+								res := ""
+								if yesFlag {
+									res = "y"
+								} else {
+									fmt.Fprint(c.outStream, "fmcsadmin: really disconnect "+strconv.Itoa(len(clients))+" client(s)? (y, n) ")
+									input, _ := c.input().ReadString('\n')
+									res = strings.ToLower(strings.TrimSpace(input))
+								}
+								if res == "y" {
+									exitStatus = disconnectClients(c, u, token, clients, message, graceTime)
+									if exitStatus == 0 {
+										fmt.Fprintln(c.outStream, "Client(s) being disconnected.")
+									}
+								}
+							}
+						}
+						c.logout(baseURI, token)
+					} else if detectHostUnreachable(exitStatus) {
+						exitStatus = 10502
+					}
 				default:
 					exitStatus = outputInvalidCommandErrorMessage(c)
 				}
@@ -876,7 +1551,7 @@ func (c *cli) Run(args []string) int {
 			}
 		case "enable":
 			if len(cmdArgs[1:]) > 0 {
-				token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+				token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 				if token != "" && exitStatus == 0 && err == nil {
 					switch strings.ToLower(cmdArgs[1]) {
 					case "schedule":
@@ -892,15 +1567,23 @@ func (c *cli) Run(args []string) int {
 							exitStatus, _, err = sendRequest("PATCH", u.String(), token, params{command: "enable"})
 							if exitStatus == 0 && err == nil {
 								u.Path = path.Join(getAPIBasePath(), "schedules")
-								exitStatus = listSchedules(u.String(), token, id)
+								exitStatus = listSchedules(c, u.String(), token, id, output.resolve(baseURI, usingCloud))
 							}
 						} else {
 							exitStatus = 10600
 						}
+					case "plugin":
+						if !requireCapability(c, baseURI, token, "enable plugin", usingCloud) {
+							exitStatus = 21
+						} else if len(cmdArgs[2:]) > 0 {
+							exitStatus = changePluginStatus(c, u, token, cmdArgs[2:], true)
+						} else {
+							exitStatus = 10007
+						}
 					default:
 						exitStatus = 11002
 					}
-					logout(baseURI, token)
+					c.logout(baseURI, token)
 				} else if detectHostUnreachable(exitStatus) {
 					exitStatus = 10502
 				}
@@ -914,7 +1597,7 @@ func (c *cli) Run(args []string) int {
 					if usingCloud {
 						exitStatus = 21
 					} else {
-						token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+						token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 						if token != "" && exitStatus == 0 && err == nil {
 							id := 0
 							if len(cmdArgs) >= 3 {
@@ -924,12 +1607,41 @@ func (c *cli) Run(args []string) int {
 								}
 							}
 							u.Path = path.Join(getAPIBasePath(), "schedules")
-							exitStatus = getBackupTime(u.String(), token, id)
-							logout(baseURI, token)
+							exitStatus = getBackupTime(c, u.String(), token, id, output.resolve(baseURI, usingCloud))
+							c.logout(baseURI, token)
 						} else if detectHostUnreachable(exitStatus) {
 							exitStatus = 10502
 						}
 					}
+				case "connectorconfig":
+					if usingCloud {
+						exitStatus = 21
+					} else {
+						printOptions := []string{}
+						for _, option := range cmdArgs[2:] {
+							if _, ok := getConnectorSetting(option); !ok {
+								fmt.Fprintln(c.outStream, "Invalid configuration name: "+option)
+								exitStatus = 10001
+								break
+							}
+							printOptions = append(printOptions, strings.ToLower(option))
+						}
+						if len(printOptions) == 0 {
+							for _, setting := range connectorSettings {
+								printOptions = append(printOptions, setting.name)
+							}
+						}
+
+						if exitStatus == 0 {
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+							if token != "" && exitStatus == 0 && err == nil {
+								exitStatus = getConnectorConfigurations(c, baseURI, token, printOptions, len(cmdArgs[2:]) > 0)
+								c.logout(baseURI, token)
+							} else if detectHostUnreachable(exitStatus) {
+								exitStatus = 10502
+							}
+						}
+					}
 				case "cwpconfig":
 					if usingCloud {
 						exitStatus = 21
@@ -984,19 +1696,16 @@ func (c *cli) Run(args []string) int {
 						}
 
 						if exitStatus == 0 {
-							token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 							if token != "" && exitStatus == 0 && err == nil {
-								u.Path = path.Join(getAPIBasePath(), "server", "metadata")
-								version := getServerVersion(u.String(), token)
-								if runtime.GOOS == "linux" && fqdn == "" && version < 19.6 {
-									// Not Supported
+								if !requireCapability(c, baseURI, token, "cwpconfig", usingCloud) {
 									exitStatus = 21
 								} else {
 									if exitStatus == 0 {
 										_, exitStatus, _ = getWebTechnologyConfigurations(baseURI, getAPIBasePath(), token, printOptions)
 									}
 								}
-								logout(baseURI, token)
+								c.logout(baseURI, token)
 							} else if detectHostUnreachable(exitStatus) {
 								exitStatus = 10502
 							}
@@ -1004,9 +1713,9 @@ func (c *cli) Run(args []string) int {
 					}
 				case "refreshtoken":
 					if usingCloud {
-						token, exitStatus, err = login(baseURI, username, password, params{printRefreshToken: true, retry: retry, identityFile: identityFile})
+						token, exitStatus, err = c.login(baseURI, username, password, params{printRefreshToken: true, retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 						if token != "" && exitStatus == 0 && err == nil {
-							logout(baseURI, token)
+							c.logout(baseURI, token)
 						} else if detectHostUnreachable(exitStatus) {
 							exitStatus = 10502
 						}
@@ -1036,7 +1745,7 @@ func (c *cli) Run(args []string) int {
 						}
 
 						if exitStatus == 0 {
-							token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 							if token != "" && exitStatus == 0 && err == nil {
 								printOptions := []string{}
 								if len(cmdArgs[2:]) > 0 {
@@ -1070,7 +1779,7 @@ func (c *cli) Run(args []string) int {
 									u.Path = path.Join(getAPIBasePath(), "server", "config", "general")
 									_, exitStatus = getServerGeneralConfigurations(u.String(), token, printOptions)
 								}
-								logout(baseURI, token)
+								c.logout(baseURI, token)
 							} else if detectHostUnreachable(exitStatus) {
 								exitStatus = 10502
 							}
@@ -1104,10 +1813,9 @@ func (c *cli) Run(args []string) int {
 					}
 
 					if exitStatus == 0 {
-						token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+						token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 						if token != "" && exitStatus == 0 && err == nil {
 							var versionString string
-							var version float64
 
 							printOptions := []string{}
 							if usingCloud {
@@ -1117,7 +1825,6 @@ func (c *cli) Run(args []string) int {
 								// for Claris FileMaker Server
 								u.Path = path.Join(getAPIBasePath(), "server", "metadata")
 								versionString, _ = getServerVersionString(u.String(), token)
-								version, _ = getServerVersionAsFloat(versionString)
 
 								if len(cmdArgs[2:]) > 0 {
 									for i := 0; i < len(cmdArgs[2:]); i++ {
@@ -1163,28 +1870,15 @@ func (c *cli) Run(args []string) int {
 									printOptions = append(printOptions, "cachesize")
 									printOptions = append(printOptions, "allowpsos")
 									printOptions = append(printOptions, "requiresecuredb")
-									printOptions = append(printOptions, "startuprestorationenabled")
-									if (version >= 19.3 && !strings.HasPrefix(versionString, "19.3.1")) || usingCloud {
-										printOptions = append(printOptions, "authenticatedstream")
-									}
-									if !usingCloud && version >= 19.5 {
-										printOptions = append(printOptions, "parallelbackupenabled")
-									}
-									if !usingCloud && version >= 20.1 {
-										printOptions = append(printOptions, "persistcacheenabled")
-										printOptions = append(printOptions, "syncpersistcache")
-									}
-									if !usingCloud && version >= 21.0 {
-										printOptions = append(printOptions, "databaseserverautorestart")
-										printOptions = append(printOptions, "blocknewusersenabled")
-									}
-									if !usingCloud && version >= 21.1 {
-										printOptions = append(printOptions, "enablehttpprotocolnetwork")
-										printOptions = append(printOptions, "onlyopenlastopeneddatabases")
+									for _, capability := range capabilities {
+										if strings.HasPrefix(capability.name, "serverprefs ") && isSupported(capability.name, versionString, usingCloud) {
+											printOptions = append(printOptions, strings.TrimPrefix(capability.name, "serverprefs "))
+										}
 									}
 								}
 
-								if version >= 19.2 && startupRestoration {
+								if startupRestoration && !isSupported("serverprefs startuprestorationenabled", versionString, usingCloud) {
+									outputCapabilityErrorMessage(c, "serverprefs startuprestorationenabled", versionString, usingCloud)
 									exitStatus = 3
 								}
 							}
@@ -1196,70 +1890,24 @@ func (c *cli) Run(args []string) int {
 								}
 
 								for _, option := range printOptions {
-									if option == "authenticatedstream" {
-										if usingCloud {
-											// for Claris FileMaker Cloud
-											u.Path = path.Join(getAPIBasePath(), "server", "config", "authenticatedstream")
-											_, exitStatus, _ = getAuthenticatedStreamSetting(u.String(), token, printOptions)
-										} else {
-											// for Claris FileMaker Server
-											if version < 19.3 || strings.HasPrefix(versionString, "19.3.1") {
-												exitStatus = 10001
-											}
-										}
-									}
-
-									if option == "parallelbackupenabled" {
-										if usingCloud {
-											// for Claris FileMaker Cloud
-											exitStatus = 3
-										} else {
-											// for Claris FileMaker Server
-											if version < 19.5 {
-												exitStatus = 10001
-											}
-										}
-									}
-
-									if option == "persistcacheenabled" || option == "syncpersistcache" {
-										if usingCloud {
-											// for Claris FileMaker Cloud
-											exitStatus = 3
-										} else {
-											// for Claris FileMaker Server
-											if version < 20.1 {
-												exitStatus = 10001
-											}
-										}
-									}
-
-									if option == "databaseserverautorestart" || option == "blocknewusersenabled" {
-										if usingCloud {
-											// for Claris FileMaker Cloud
-											exitStatus = 3
-										} else {
-											// for Claris FileMaker Server
-											if version < 21.0 {
-												exitStatus = 10001
-											}
-										}
-									}
-
-									if option == "enablehttpprotocolnetwork" || option == "onlyopenlastopeneddatabases" {
+									if option == "authenticatedstream" && usingCloud {
+										// for Claris FileMaker Cloud
+										u.Path = path.Join(getAPIBasePath(), "server", "config", "authenticatedstream")
+										_, exitStatus, _ = getAuthenticatedStreamSetting(u.String(), token, printOptions)
+									} else if _, ok := getCapability("serverprefs " + option); ok && !isSupported("serverprefs "+option, versionString, usingCloud) {
+										outputCapabilityErrorMessage(c, "serverprefs "+option, versionString, usingCloud)
 										if usingCloud {
 											// for Claris FileMaker Cloud
 											exitStatus = 3
 										} else {
 											// for Claris FileMaker Server
-											if version < 21.1 {
-												exitStatus = 10001
-											}
+											exitStatus = 10001
 										}
 									}
 								}
 							}
 
-							logout(baseURI, token)
+							c.logout(baseURI, token)
 						} else if detectHostUnreachable(exitStatus) {
 							exitStatus = 10502
 						}
@@ -1277,12 +1925,20 @@ func (c *cli) Run(args []string) int {
 					fmt.Fprint(c.outStream, commandListHelpTextTemplate)
 				case "options":
 					fmt.Fprint(c.outStream, optionListHelpTextTemplate)
+				case "error":
+					exitStatus = showErrorCodes(c, cmdArgs[2:])
 				case "cancel":
 					fmt.Fprint(c.outStream, cancelHelpTextTemplate)
 				case "certificate":
 					fmt.Fprint(c.outStream, certificateHelpTextTemplate)
+				case "check":
+					fmt.Fprint(c.outStream, checkHelpTextTemplate)
 				case "close":
 					fmt.Fprint(c.outStream, closeHelpTextTemplate)
+				case "completion":
+					fmt.Fprint(c.outStream, completionHelpTextTemplate)
+				case "credential":
+					fmt.Fprint(c.outStream, credentialHelpTextTemplate)
 				case "delete":
 					fmt.Fprint(c.outStream, deleteHelpTextTemplate)
 				case "disable":
@@ -1292,9 +1948,13 @@ func (c *cli) Run(args []string) int {
 				case "enable":
 					fmt.Fprint(c.outStream, enableHelpTextTemplate)
 				case "get":
-					fmt.Fprint(c.outStream, getHelpTextTemplate)
+					fmt.Fprintf(c.outStream, getHelpTextTemplate, getSettingRulesHelpText())
 				case "help":
 					fmt.Fprint(c.outStream, helpTextTemplate)
+				case "info":
+					fmt.Fprint(c.outStream, infoHelpTextTemplate)
+				case "lint":
+					fmt.Fprint(c.outStream, lintHelpTextTemplate)
 				case "list":
 					fmt.Fprint(c.outStream, listHelpTextTemplate)
 				case "open":
@@ -1305,6 +1965,8 @@ func (c *cli) Run(args []string) int {
 					fmt.Fprint(c.outStream, removeHelpTextTemplate)
 				case "restart":
 					fmt.Fprint(c.outStream, restartHelpTextTemplate)
+				case "restore":
+					fmt.Fprint(c.outStream, restoreHelpTextTemplate)
 				case "resume":
 					fmt.Fprint(c.outStream, resumeHelpTextTemplate)
 				case "run":
@@ -1312,7 +1974,9 @@ func (c *cli) Run(args []string) int {
 				case "send":
 					fmt.Fprint(c.outStream, sendHelpTextTemplate)
 				case "set":
-					fmt.Fprint(c.outStream, setHelpTextTemplate)
+					fmt.Fprintf(c.outStream, setHelpTextTemplate, getSettingRulesHelpText())
+				case "shell":
+					fmt.Fprint(c.outStream, shellHelpTextTemplate)
 				case "start":
 					fmt.Fprint(c.outStream, startHelpTextTemplate)
 				case "status":
@@ -1325,32 +1989,88 @@ func (c *cli) Run(args []string) int {
 			} else {
 				fmt.Fprint(c.outStream, helpTextTemplate)
 			}
+		case "info":
+			token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+			if token != "" && exitStatus == 0 && err == nil {
+				exitStatus = showServerInfo(c, baseURI, token, usingCloud)
+				c.logout(baseURI, token)
+			} else if detectHostUnreachable(exitStatus) {
+				exitStatus = 10502
+			}
+		case "lint":
+			if usingCloud {
+				exitStatus = 21
+			} else if len(policyFile) == 0 || len(cmdArgs[1:]) > 0 {
+				exitStatus = outputInvalidCommandErrorMessage(c)
+			} else {
+				policy, err := readLintPolicy(policyFile)
+				if errors.Is(err, os.ErrNotExist) {
+					exitStatus = 20405
+				} else if err != nil {
+					fmt.Fprintln(c.outStream, "fmcsadmin: invalid policy: "+err.Error())
+					exitStatus = 10001
+				} else {
+					token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+					if token != "" && exitStatus == 0 && err == nil {
+						exitStatus = lintServer(c, baseURI, token, policy, maintenanceFlag)
+						c.logout(baseURI, token)
+					} else if detectHostUnreachable(exitStatus) {
+						exitStatus = 10502
+					}
+				}
+			}
 		case "list":
 			if len(cmdArgs[1:]) > 0 {
 				switch strings.ToLower(cmdArgs[1]) {
+				case "backups":
+					if usingCloud {
+						exitStatus = 21
+					} else {
+						token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+						if token != "" && exitStatus == 0 && err == nil {
+							u.Path = path.Join(getAPIBasePath(), "schedules")
+							exitStatus = listBackups(c, u.String(), token, scheduleID)
+							c.logout(baseURI, token)
+						} else if detectHostUnreachable(exitStatus) {
+							exitStatus = 10502
+						}
+					}
 				case "clients":
-					token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+					opts := clientListOptions{
+						fileName:   fileFilter,
+						userName:   userFilter,
+						appVersion: appVersionFilter,
+						ipAddress:  ipFilter,
+						sortKey:    sortKey,
+					}
+					if option := validateClientListOptions(opts); option != "" {
+						fmt.Fprintln(c.outStream, "Invalid parameter for option: "+option)
+						exitStatus = 10001
+						break
+					}
+					token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 					if token != "" && exitStatus == 0 && err == nil {
 						id := -1
 						if statsFlag {
 							id = 0
 						}
+						opts.outputOptions = output.resolve(baseURI, usingCloud)
 						u.Path = path.Join(getAPIBasePath(), "clients")
-						exitStatus = listClients(u.String(), token, id)
-						logout(baseURI, token)
+						exitStatus = listClients(c, u.String(), token, id, opts)
+						c.logout(baseURI, token)
 					} else if detectHostUnreachable(exitStatus) {
 						exitStatus = 10502
 					}
 				case "files":
-					token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+					token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 					if token != "" && exitStatus == 0 && err == nil {
 						idList := []int{-1}
 						if statsFlag {
 							idList = []int{0}
 						}
 						u.Path = path.Join(getAPIBasePath(), "databases")
-						exitStatus = listFiles(c, u.String(), token, idList)
-						logout(baseURI, token)
+						exitStatus = listFiles(c, u.String(), token, idList, output)
+						c.logout(baseURI, token)
 					} else if detectHostUnreachable(exitStatus) {
 						exitStatus = 10502
 					}
@@ -1358,13 +2078,11 @@ func (c *cli) Run(args []string) int {
 					if usingCloud {
 						exitStatus = 21
 					} else {
-						token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+						token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 						if token != "" && exitStatus == 0 && err == nil {
-							u.Path = path.Join(getAPIBasePath(), "server", "metadata")
-							version := getServerVersion(u.String(), token)
-							if version >= 19.2 {
+							if requireCapability(c, baseURI, token, "list plugins", usingCloud) {
 								u.Path = path.Join(getAPIBasePath(), "plugins")
-								exitStatus = listPlugins(u.String(), token)
+								exitStatus = listPlugins(c, u.String(), token)
 							} else {
 								var running string
 								u.Path = path.Join(getAPIBasePath(), "server", "status")
@@ -1375,17 +2093,17 @@ func (c *cli) Run(args []string) int {
 									exitStatus = outputInvalidCommandErrorMessage(c)
 								}
 							}
-							logout(baseURI, token)
+							c.logout(baseURI, token)
 						} else if detectHostUnreachable(exitStatus) {
 							exitStatus = 10502
 						}
 					}
 				case "schedules":
-					token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+					token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 					if token != "" && exitStatus == 0 && err == nil {
 						u.Path = path.Join(getAPIBasePath(), "schedules")
-						exitStatus = listSchedules(u.String(), token, 0)
-						logout(baseURI, token)
+						exitStatus = listSchedules(c, u.String(), token, 0, output.resolve(baseURI, usingCloud))
+						c.logout(baseURI, token)
 					} else if detectHostUnreachable(exitStatus) {
 						exitStatus = 10502
 					}
@@ -1396,59 +2114,19 @@ func (c *cli) Run(args []string) int {
 				exitStatus = outputInvalidCommandErrorMessage(c)
 			}
 		case "open":
-			token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+			token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 			if token != "" && exitStatus == 0 && err == nil {
-				u.Path = path.Join(getAPIBasePath(), "databases")
 				args = []string{""}
 				if len(cmdArgs[1:]) > 0 {
 					args = cmdArgs[1:]
 				}
-				idList, nameList, hintList := getDatabases(u.String(), token, args, "CLOSED", false)
-				if len(idList) > 0 {
-					if usingCloud && (len(key) > 0 || saveKeyFlag) {
-						if len(key) > 0 {
-							exitStatus = outputInvalidOptionErrorMessage(c, "--key")
-						} else {
-							exitStatus = outputInvalidOptionErrorMessage(c, "--savekey")
-						}
-					} else {
-						for i := 0; i < len(idList); i++ {
-							fmt.Fprintln(c.outStream, "File Opening: "+nameList[i])
-						}
-						for i := 0; i < len(idList); i++ {
-							u.Path = path.Join(getAPIBasePath(), "databases", strconv.Itoa(idList[i]))
-							exitStatus, _, err = sendRequest("PATCH", u.String(), token, params{command: "open", key: key, saveKey: saveKeyFlag})
-							if exitStatus == 0 && err == nil {
-								// Note: FileMaker Admin API does not validate the encryption key.
-								//       You receive a result code of 0 even if you enter an invalid key.
-								var openedID []int
-								for value := 0; ; {
-									value++
-									u.Path = path.Join(getAPIBasePath(), "databases")
-									openedID, _, _ = getDatabases(u.String(), token, []string{strconv.Itoa(idList[i])}, "NORMAL", false)
-									if len(openedID) > 0 || value > 3 {
-										break
-									}
-									time.Sleep(1 * time.Second)
-								}
-								if len(openedID) > 0 {
-									fmt.Fprintln(c.outStream, "File Opened: "+nameList[i])
-								} else {
-									fmt.Fprintln(c.outStream, "Fail to open encrypted database. The correct password must be supplied with the --key option. (Hint: "+hintList[i]+")")
-									fmt.Fprintln(c.outStream, "File Closed: "+nameList[i])
-								}
-							}
-						}
-					}
-				} else {
-					exitStatus = 10904
-				}
-				logout(baseURI, token)
+				exitStatus = openDatabases(c, u, token, args, key, saveKeyFlag, usingCloud)
+				c.logout(baseURI, token)
 			} else if detectHostUnreachable(exitStatus) {
 				exitStatus = 10502
 			}
 		case "pause":
-			token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+			token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 			if token != "" && exitStatus == 0 && err == nil {
 				u.Path = path.Join(getAPIBasePath(), "databases")
 				args = []string{""}
@@ -1460,17 +2138,19 @@ func (c *cli) Run(args []string) int {
 					for i := 0; i < len(idList); i++ {
 						fmt.Fprintln(c.outStream, "File Pausing: "+nameList[i])
 					}
+					var result batchResult
 					for i := 0; i < len(idList); i++ {
 						u.Path = path.Join(getAPIBasePath(), "databases", strconv.Itoa(idList[i]))
 						exitStatus, _, err = sendRequest("PATCH", u.String(), token, params{command: "pause"})
-						if exitStatus == 0 && err == nil {
+						if result.add(exitStatus, err) {
 							fmt.Fprintln(c.outStream, "File Paused: "+nameList[i])
 						}
 					}
+					exitStatus = result.getExitStatus(c)
 				} else {
 					exitStatus = 10904
 				}
-				logout(baseURI, token)
+				c.logout(baseURI, token)
 			} else if detectHostUnreachable(exitStatus) {
 				exitStatus = 10502
 			}
@@ -1479,20 +2159,14 @@ func (c *cli) Run(args []string) int {
 			if yesFlag {
 				res = "y"
 			} else {
-				r := bufio.NewReader(os.Stdin)
 				fmt.Fprint(c.outStream, "fmcsadmin: really remove database(s)? (y, n) ")
-				input, _ := r.ReadString('\n')
+				input, _ := c.input().ReadString('\n')
 				res = strings.ToLower(strings.TrimSpace(input))
 			}
 			if res == "y" {
-				token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+				token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 				if token != "" && exitStatus == 0 && err == nil {
-					var version float64
-					if !usingCloud {
-						u.Path = path.Join(getAPIBasePath(), "server", "metadata")
-						version = getServerVersion(u.String(), token)
-					}
-					if version >= 19.3 || usingCloud {
+					if requireCapability(c, baseURI, token, "remove", usingCloud) {
 						u.Path = path.Join(getAPIBasePath(), "databases")
 						args = []string{""}
 						if len(cmdArgs[1:]) > 0 {
@@ -1500,13 +2174,15 @@ func (c *cli) Run(args []string) int {
 						}
 						idList, nameList, _ := getDatabases(u.String(), token, args, "CLOSED", true)
 						if len(idList) > 0 {
+							var result batchResult
 							for i := 0; i < len(idList); i++ {
 								u.Path = path.Join(getAPIBasePath(), "databases", strconv.Itoa(idList[i]))
 								exitStatus, _, err = sendRequest("DELETE", u.String(), token, params{})
-								if exitStatus == 0 && err == nil {
+								if result.add(exitStatus, err) {
 									fmt.Fprintln(c.outStream, "File Removed: "+nameList[i])
 								}
 							}
+							exitStatus = result.getExitStatus(c)
 						} else {
 							_, nameList, _ = getDatabases(u.String(), token, args, "", true)
 							exitStatus = 10904
@@ -1526,7 +2202,7 @@ func (c *cli) Run(args []string) int {
 					} else {
 						exitStatus = outputInvalidCommandErrorMessage(c)
 					}
-					logout(baseURI, token)
+					c.logout(baseURI, token)
 				} else if detectHostUnreachable(exitStatus) {
 					exitStatus = 10502
 				}
@@ -1540,15 +2216,14 @@ func (c *cli) Run(args []string) int {
 					if yesFlag {
 						res = "y"
 					} else {
-						r := bufio.NewReader(os.Stdin)
 						fmt.Fprint(c.outStream, "fmcsadmin: really restart server? (y, n) ")
-						input, _ := r.ReadString('\n')
+						input, _ := c.input().ReadString('\n')
 						res = strings.ToLower(strings.TrimSpace(input))
 					}
 					if res == "y" {
 						switch strings.ToLower(cmdArgs[1]) {
 						case "server":
-							token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 							if token != "" && exitStatus == 0 && err == nil {
 								// stop database server
 								if forceFlag {
@@ -1556,11 +2231,14 @@ func (c *cli) Run(args []string) int {
 								}
 								exitStatus, _ = stopDatabaseServer(u, token, message, graceTime)
 								if exitStatus == 0 {
-									_, _ = waitStoppingServer(u, token)
-									// start database server
-									exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{status: "RUNNING"})
+									if _, err := waitStoppingServer(u, token); err == errWaitTimeout {
+										exitStatus = c.timedOut("the server to stop")
+									} else {
+										// start database server
+										exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{status: "RUNNING"})
+									}
 								}
-								logout(baseURI, token)
+								c.logout(baseURI, token)
 							} else if detectHostUnreachable(exitStatus) {
 								exitStatus = 10502
 							}
@@ -1572,8 +2250,26 @@ func (c *cli) Run(args []string) int {
 					exitStatus = outputInvalidCommandErrorMessage(c)
 				}
 			}
+		case "restore":
+			if usingCloud {
+				exitStatus = 21
+			} else if len(fqdn) > 0 {
+				// the files of the backup set are copied on this machine
+				fmt.Fprintln(c.outStream, "fmcsadmin: RESTORE must be run on the machine running FileMaker Server.")
+				exitStatus = 21
+			} else if len(cmdArgs[2:]) > 0 {
+				token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+				if token != "" && exitStatus == 0 && err == nil {
+					exitStatus = restoreDatabases(c, u, token, cmdArgs[1], cmdArgs[2:], dryRun, yesFlag, message, forceFlag, key, saveKeyFlag)
+					c.logout(baseURI, token)
+				} else if detectHostUnreachable(exitStatus) {
+					exitStatus = 10502
+				}
+			} else {
+				exitStatus = outputInvalidCommandErrorMessage(c)
+			}
 		case "resume":
-			token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+			token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 			if token != "" && exitStatus == 0 && err == nil {
 				u.Path = path.Join(getAPIBasePath(), "databases")
 				args = []string{""}
@@ -1585,17 +2281,19 @@ func (c *cli) Run(args []string) int {
 					for i := 0; i < len(idList); i++ {
 						fmt.Fprintln(c.outStream, "File Resuming: "+nameList[i])
 					}
+					var result batchResult
 					for i := 0; i < len(idList); i++ {
 						u.Path = path.Join(getAPIBasePath(), "databases", strconv.Itoa(idList[i]))
 						exitStatus, _, err = sendRequest("PATCH", u.String(), token, params{command: "resume"})
-						if exitStatus == 0 && err == nil {
+						if result.add(exitStatus, err) {
 							fmt.Fprintln(c.outStream, "File Resumed: "+nameList[i])
 						}
 					}
+					exitStatus = result.getExitStatus(c)
 				} else {
 					exitStatus = 10904
 				}
-				logout(baseURI, token)
+				c.logout(baseURI, token)
 			} else if detectHostUnreachable(exitStatus) {
 				exitStatus = 10502
 			}
@@ -1603,7 +2301,7 @@ func (c *cli) Run(args []string) int {
 			if len(cmdArgs[1:]) > 0 {
 				switch strings.ToLower(cmdArgs[1]) {
 				case "schedule":
-					token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+					token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 					if token != "" && exitStatus == 0 && err == nil {
 						id := 0
 						if len(cmdArgs) >= 3 {
@@ -1629,7 +2327,7 @@ func (c *cli) Run(args []string) int {
 						} else {
 							exitStatus = 10600
 						}
-						logout(baseURI, token)
+						c.logout(baseURI, token)
 					} else if detectHostUnreachable(exitStatus) {
 						exitStatus = 10502
 					}
@@ -1640,16 +2338,78 @@ func (c *cli) Run(args []string) int {
 				exitStatus = outputInvalidCommandErrorMessage(c)
 			}
 		case "send":
-			token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
-			if token != "" && exitStatus == 0 && err == nil {
-				exitStatus = sendMessages(u, token, message, cmdArgs, clientID)
-				logout(baseURI, token)
-			} else if detectHostUnreachable(exitStatus) {
-				exitStatus = 10502
+			if len(at) > 0 || len(repeat) > 0 || len(until) > 0 {
+				schedule, deadline, option := getMessageSchedule(at, repeat, until, timeNow())
+				if option == "" && strings.Contains(message, "{remaining}") && deadline.IsZero() {
+					option = "--until"
+				}
+				if option != "" {
+					fmt.Fprintln(c.outStream, "Invalid parameter for option: "+option)
+					exitStatus = 10001
+					break
+				}
+
+				// resolve the credentials once and check them before waiting for the first delivery
+				if identityFile == "" {
+					username, password, _, exitStatus = getStoredCredentials(c.outStream, baseURI, username, password, credentialHelper)
+					if exitStatus != 0 {
+						break
+					}
+					product := 1
+					if usingCloud {
+						product = 2
+					}
+					username, password, _ = getUsernameAndPassword(username, password, product)
+				}
+				token, exitStatus, err = c.login(baseURI, username, password, params{identityFile: identityFile, credentialHelper: credentialHelper})
+				if token != "" && exitStatus == 0 && err == nil {
+					c.logout(baseURI, token)
+					fmt.Fprintln(c.outStream, "Sending the message "+strconv.Itoa(len(schedule))+" time(s) from "+schedule[0].Format("2006/01/02 15:04:05")+" until "+schedule[len(schedule)-1].Format("2006/01/02 15:04:05")+".")
+					exitStatus = sendScheduledMessages(c, baseURI, username, password, params{identityFile: identityFile, credentialHelper: credentialHelper}, message, cmdArgs, clientID, schedule, deadline)
+				} else if detectHostUnreachable(exitStatus) {
+					exitStatus = 10502
+				}
+			} else {
+				token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+				if token != "" && exitStatus == 0 && err == nil {
+					exitStatus = sendMessages(u, token, message, cmdArgs, clientID)
+					c.logout(baseURI, token)
+				} else if detectHostUnreachable(exitStatus) {
+					exitStatus = 10502
+				}
 			}
 		case "set":
 			if len(cmdArgs[1:]) > 0 {
 				switch strings.ToLower(cmdArgs[1]) {
+				case "connectorconfig":
+					if usingCloud {
+						exitStatus = 21
+					} else if len(cmdArgs[2:]) > 0 {
+						for _, arg := range cmdArgs[2:] {
+							option, _, found := strings.Cut(arg, "=")
+							if !found {
+								exitStatus = 10001
+								break
+							}
+							if _, ok := getConnectorSetting(option); !ok {
+								fmt.Fprintln(c.outStream, "Invalid configuration name: "+option)
+								exitStatus = 10001
+								break
+							}
+						}
+
+						if exitStatus == 0 {
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+							if token != "" && exitStatus == 0 && err == nil {
+								exitStatus = setConnectorConfigurations(c, baseURI, token, cmdArgs[2:])
+								c.logout(baseURI, token)
+							} else if detectHostUnreachable(exitStatus) {
+								exitStatus = 10502
+							}
+						}
+					} else {
+						exitStatus = 10001
+					}
 				case "cwpconfig":
 					if usingCloud {
 						exitStatus = 21
@@ -1676,12 +2436,9 @@ func (c *cli) Run(args []string) int {
 							}
 
 							if exitStatus == 0 {
-								token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+								token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 								if token != "" && exitStatus == 0 && err == nil {
-									u.Path = path.Join(getAPIBasePath(), "server", "metadata")
-									version := getServerVersion(u.String(), token)
-									if runtime.GOOS == "linux" && fqdn == "" && version < 19.6 {
-										// Not Supported
+									if !requireCapability(c, baseURI, token, "cwpconfig", usingCloud) {
 										exitStatus = 10001
 									} else {
 										var settings []string
@@ -1858,7 +2615,7 @@ func (c *cli) Run(args []string) int {
 											}
 										}
 									}
-									logout(baseURI, token)
+									c.logout(baseURI, token)
 								} else if detectHostUnreachable(exitStatus) {
 									exitStatus = 10502
 								}
@@ -1895,7 +2652,7 @@ func (c *cli) Run(args []string) int {
 						}
 
 						if exitStatus == 0 {
-							token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 							if token != "" && exitStatus == 0 && err == nil {
 								var settings []int
 								printOptions := []string{}
@@ -1905,53 +2662,35 @@ func (c *cli) Run(args []string) int {
 									var results []string
 									results, exitStatus = parseServerConfigurationSettings(cmdArgs[2:])
 
+									u.Path = path.Join(getAPIBasePath(), "server", "metadata")
+									versionString, _ := getServerVersionString(u.String(), token)
+									version, _ := parseServerVersion(versionString)
+									if result := validateServerSettings(c, cmdArgs[2:], version, versionString, map[string]string{}); result != 0 {
+										exitStatus = result
+									}
+
 									cacheSize, _ := strconv.Atoi(results[0])
 									maxFiles, _ := strconv.Atoi(results[1])
 									maxProConnections, _ := strconv.Atoi(results[2])
 									maxPSOS, _ := strconv.Atoi(results[3])
 									startupRestorationEnabled := results[4]
 									secureFilesOnlyFlag := results[5]
-									authenticatedStream, _ := strconv.Atoi(results[6])
 
 									if results[0] != "" || results[1] != "" || results[2] != "" || results[3] != "" || startupRestorationEnabled != "" || secureFilesOnlyFlag != "" || results[6] != "" {
 										if results[0] == "" {
 											cacheSize = settings[0]
-										} else {
-											if cacheSize < 64 || cacheSize > 1048576 {
-												exitStatus = 10001
-											}
 										}
 
 										if results[1] == "" {
 											maxFiles = settings[1]
-										} else {
-											u.Path = path.Join(getAPIBasePath(), "server", "metadata")
-											version := getServerVersion(u.String(), token)
-											if version >= 20.1 {
-												if maxFiles < 1 || maxFiles > 256 {
-													exitStatus = 10001
-												}
-											} else {
-												if maxFiles < 1 || maxFiles > 125 {
-													exitStatus = 10001
-												}
-											}
 										}
 
 										if results[2] == "" {
 											maxProConnections = settings[2]
-										} else {
-											if maxProConnections < 0 || maxProConnections > 2000 {
-												exitStatus = 10001
-											}
 										}
 
 										if results[3] == "" {
 											maxPSOS = settings[3]
-										} else {
-											if maxPSOS < 0 || maxPSOS > 500 {
-												exitStatus = 10001
-											}
 										}
 
 										startupRestorationBuiltin := true
@@ -1960,13 +2699,6 @@ func (c *cli) Run(args []string) int {
 											startupRestorationBuiltin = false
 										}
 
-										if results[6] != "" {
-											// for Claris FileMaker Server 19.3.2 or later
-											if authenticatedStream < 1 || authenticatedStream > 2 {
-												exitStatus = 10001
-											}
-										}
-
 										printOptions = []string{}
 										if len(cmdArgs[2:]) > 0 {
 											for i := 0; i < len(cmdArgs[2:]); i++ {
@@ -2003,23 +2735,23 @@ func (c *cli) Run(args []string) int {
 											printOptions = append(printOptions, "authenticatedstream")
 										}
 										if exitStatus == 0 {
+											var changes []settingChange
 											if results[0] != "" || results[1] != "" || results[2] != "" || results[3] != "" {
-												u.Path = path.Join(getAPIBasePath(), "server", "config", "general")
-												exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{
+												changes = append(changes, newSettingChange("server/config/general", cmdArgs[2:], params{
 													command:                   "set",
 													cachesize:                 cacheSize,
 													maxfiles:                  maxFiles,
 													maxproconnections:         maxProConnections,
 													maxpsos:                   maxPSOS,
 													startuprestorationbuiltin: startupRestorationBuiltin,
-												})
+												}))
 											}
 
-											if exitStatus == 0 && (secureFilesOnlyFlag == "true" || secureFilesOnlyFlag == "false") {
-												u.Path = path.Join(getAPIBasePath(), "server", "config", "security")
-												exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{command: "set", requiresecuredb: secureFilesOnlyFlag})
+											if secureFilesOnlyFlag == "true" || secureFilesOnlyFlag == "false" {
+												changes = append(changes, newSettingChange("server/config/security", cmdArgs[2:], params{command: "set", requiresecuredb: secureFilesOnlyFlag}))
 											}
 
+											exitStatus = applySettingChanges(c, baseURI, token, changes)
 											if exitStatus == 0 {
 												u.Path = path.Join(getAPIBasePath(), "server", "config", "general")
 												_, exitStatus = getServerGeneralConfigurations(u.String(), token, printOptions)
@@ -2027,7 +2759,7 @@ func (c *cli) Run(args []string) int {
 										}
 									}
 								}
-								logout(baseURI, token)
+								c.logout(baseURI, token)
 							} else if detectHostUnreachable(exitStatus) {
 								exitStatus = 10502
 							}
@@ -2063,15 +2795,15 @@ func (c *cli) Run(args []string) int {
 					}
 
 					if exitStatus == 0 {
-						token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+						token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 						if token != "" && exitStatus == 0 && err == nil {
 							var versionString string
-							var version float64
+							var version serverVersion
 
 							if !usingCloud {
 								u.Path = path.Join(getAPIBasePath(), "server", "metadata")
 								versionString, _ = getServerVersionString(u.String(), token)
-								version, _ = getServerVersionAsFloat(versionString)
+								version, _ = parseServerVersion(versionString)
 							}
 
 							var results []string
@@ -2103,13 +2835,11 @@ func (c *cli) Run(args []string) int {
 								}
 
 								results, exitStatus = parseServerConfigurationSettings(cmdArgs[2:])
+								if result := validateServerSettings(c, cmdArgs[2:], version, versionString, map[string]string{}); result != 0 {
+									exitStatus = result
+								}
 
 								authenticatedStream, _ := strconv.Atoi(results[6])
-								if results[6] != "" {
-									if authenticatedStream < 1 || authenticatedStream > 2 {
-										exitStatus = 10001
-									}
-								}
 								if exitStatus == 0 {
 									u.Path = path.Join(getAPIBasePath(), "server", "config", "authenticatedstream")
 									exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{command: "set", authenticatedstream: authenticatedStream})
@@ -2127,6 +2857,18 @@ func (c *cli) Run(args []string) int {
 									var results []string
 									results, exitStatus = parseServerConfigurationSettings(cmdArgs[2:])
 
+									current := map[string]string{}
+									if (results[9] != "" || results[10] != "") && results[8] == "" && isSupported("set serverprefs persistcacheenabled", versionString, usingCloud) {
+										u.Path = path.Join(getAPIBasePath(), "server", "config", "persistentcache")
+										persistentCacheSettings, result, _ := getPersistentCacheConfigurations(u.String(), token, noPrintOptions)
+										if result == 0 {
+											current["persistcacheenabled"] = persistentCacheSettings[0]
+										}
+									}
+									if result := validateServerSettings(c, cmdArgs[2:], version, versionString, current); result != 0 {
+										exitStatus = result
+									}
+
 									cacheSize, _ := strconv.Atoi(results[0])
 									maxFiles, _ := strconv.Atoi(results[1])
 									maxProConnections, _ := strconv.Atoi(results[2])
@@ -2145,40 +2887,18 @@ func (c *cli) Run(args []string) int {
 									if results[0] != "" || results[1] != "" || results[2] != "" || results[3] != "" || startupRestorationEnabled != "" || secureFilesOnlyFlag != "" || results[6] != "" || parallelBackupEnabled != "" || persistCacheEnabled != "" || syncPersistCache != "" || databaseServerAutoRestart != "" || blockNewUsersEnabled != "" || enableHttpProtocolNetwork != "" || onlyOpenLastOpenedDatabases != "" {
 										if results[0] == "" {
 											cacheSize = settings[0]
-										} else {
-											if cacheSize < 64 || cacheSize > 1048576 {
-												exitStatus = 10001
-											}
 										}
 
 										if results[1] == "" {
 											maxFiles = settings[1]
-										} else {
-											if version >= 20.1 {
-												if maxFiles < 1 || maxFiles > 256 {
-													exitStatus = 10001
-												}
-											} else {
-												if maxFiles < 1 || maxFiles > 125 {
-													exitStatus = 10001
-												}
-											}
 										}
 
 										if results[2] == "" {
 											maxProConnections = settings[2]
-										} else {
-											if maxProConnections < 0 || maxProConnections > 2000 {
-												exitStatus = 10001
-											}
 										}
 
 										if results[3] == "" {
 											maxPSOS = settings[3]
-										} else {
-											if maxPSOS < 0 || maxPSOS > 500 {
-												exitStatus = 10001
-											}
 										}
 
 										startupRestoration := false
@@ -2192,13 +2912,6 @@ func (c *cli) Run(args []string) int {
 											}
 										}
 
-										if results[6] != "" {
-											// for Claris FileMaker Server 19.3.2 or later
-											if authenticatedStream < 1 || authenticatedStream > 2 {
-												exitStatus = 10001
-											}
-										}
-
 										printOptions = []string{}
 										if len(cmdArgs[2:]) > 0 {
 											for i := 0; i < len(cmdArgs[2:]); i++ {
@@ -2218,57 +2931,17 @@ func (c *cli) Run(args []string) int {
 														if startupRestorationBuiltin {
 															printOptions = append(printOptions, "startuprestorationenabled")
 														} else {
-															// for Claris FileMaker Server 19 or later
+															// for Claris FileMaker Server 19.1.2 or later
+															outputCapabilityErrorMessage(c, "serverprefs startuprestorationenabled", versionString, usingCloud)
 															exitStatus = 3
 														}
 													case "requiresecuredb":
 														printOptions = append(printOptions, "requiresecuredb")
-													case "authenticatedstream":
-														if version >= 19.3 && !strings.HasPrefix(versionString, "19.3.1") {
-															printOptions = append(printOptions, "authenticatedstream")
-														} else {
-															exitStatus = 10001
-														}
-													case "parallelbackupenabled":
-														if version >= 19.5 {
-															printOptions = append(printOptions, "parallelbackupenabled")
-														} else {
-															exitStatus = 10001
-														}
-													case "persistcacheenabled":
-														if version >= 21.0 {
-															printOptions = append(printOptions, "persistcacheenabled")
-														} else {
-															exitStatus = 10001
-														}
-													case "syncpersistcache":
-														if version >= 21.0 {
-															printOptions = append(printOptions, "syncpersistcache")
-														} else {
-															exitStatus = 10001
-														}
-													case "databaseserverautorestart":
-														if version >= 21.0 {
-															printOptions = append(printOptions, "databaseserverautorestart")
-														} else {
-															exitStatus = 10001
-														}
-													case "blocknewusersenabled":
-														if version >= 21.0 {
-															printOptions = append(printOptions, "blocknewusersenabled")
-														} else {
-															exitStatus = 10001
-														}
-													case "enablehttpprotocolnetwork":
-														if version >= 21.1 {
-															printOptions = append(printOptions, "enablehttpprotocolnetwork")
-														} else {
-															exitStatus = 10001
-														}
-													case "onlyopenlastopeneddatabases":
-														if version >= 21.1 {
-															printOptions = append(printOptions, "onlyopenlastopeneddatabases")
+													case "authenticatedstream", "parallelbackupenabled", "persistcacheenabled", "syncpersistcache", "databaseserverautorestart", "blocknewusersenabled", "enablehttpprotocolnetwork", "onlyopenlastopeneddatabases":
+														if isSupported(getSetCapabilityName("serverprefs "+strings.ToLower(option)), versionString, usingCloud) {
+															printOptions = append(printOptions, strings.ToLower(option))
 														} else {
+															outputCapabilityErrorMessage(c, getSetCapabilityName("serverprefs "+strings.ToLower(option)), versionString, usingCloud)
 															exitStatus = 10001
 														}
 													default:
@@ -2284,31 +2957,20 @@ func (c *cli) Run(args []string) int {
 											printOptions = append(printOptions, "maxfiles")
 											printOptions = append(printOptions, "maxguests")
 											printOptions = append(printOptions, "allowpsos")
-											if startupRestorationBuiltin {
-												printOptions = append(printOptions, "startuprestorationenabled")
-											}
 											printOptions = append(printOptions, "requiresecuredb")
-											if version >= 19.3 && !strings.HasPrefix(versionString, "19.3.1") {
-												printOptions = append(printOptions, "authenticatedstream")
-											}
-											if version >= 19.5 {
-												printOptions = append(printOptions, "parallelbackupenabled")
-											}
-											if version >= 21.0 {
-												printOptions = append(printOptions, "persistcacheenabled")
-												printOptions = append(printOptions, "syncpersistcache")
-												printOptions = append(printOptions, "databaseserverautorestart")
-												printOptions = append(printOptions, "blocknewusersenabled")
-											}
-											if version >= 21.1 {
-												printOptions = append(printOptions, "enablehttpprotocolnetwork")
-												printOptions = append(printOptions, "onlyopenlastopeneddatabases")
+											for _, capability := range capabilities {
+												if capability.name == "serverprefs startuprestorationenabled" && !startupRestorationBuiltin {
+													continue
+												}
+												if strings.HasPrefix(capability.name, "serverprefs ") && isSupported(getSetCapabilityName(capability.name), versionString, usingCloud) {
+													printOptions = append(printOptions, strings.TrimPrefix(capability.name, "serverprefs "))
+												}
 											}
 										}
 										if exitStatus == 0 {
+											var changes []settingChange
 											if results[0] != "" || results[1] != "" || results[2] != "" || results[3] != "" || results[4] != "" || results[13] != "" {
-												u.Path = path.Join(getAPIBasePath(), "server", "config", "general")
-												exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{
+												changes = append(changes, newSettingChange("server/config/general", cmdArgs[2:], params{
 													command:                     "set",
 													cachesize:                   cacheSize,
 													maxfiles:                    maxFiles,
@@ -2317,115 +2979,75 @@ func (c *cli) Run(args []string) int {
 													startuprestorationenabled:   startupRestoration,
 													startuprestorationbuiltin:   startupRestorationBuiltin,
 													onlyopenlastopeneddatabases: onlyOpenLastOpenedDatabases,
-												})
+												}))
 											}
 
-											if exitStatus == 0 && (secureFilesOnlyFlag == "true" || secureFilesOnlyFlag == "false") {
-												u.Path = path.Join(getAPIBasePath(), "server", "config", "security")
-												exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{command: "set", requiresecuredb: secureFilesOnlyFlag})
+											if secureFilesOnlyFlag == "true" || secureFilesOnlyFlag == "false" {
+												changes = append(changes, newSettingChange("server/config/security", cmdArgs[2:], params{command: "set", requiresecuredb: secureFilesOnlyFlag}))
 											}
 
-											if exitStatus == 0 {
-												if results[6] != "" {
-													if version >= 19.3 && !strings.HasPrefix(versionString, "19.3.1") {
-														// for Claris FileMaker Server 19.3.2 or later
-														u.Path = path.Join(getAPIBasePath(), "server", "config", "authenticatedstream")
-														exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{command: "set", authenticatedstream: authenticatedStream})
-														if exitStatus != 0 {
-															exitStatus = 10001
-														}
-													} else {
-														exitStatus = 3
-													}
-												}
-
-												if results[7] != "" {
-													// for Claris FileMaker Server 19.5.1 or later
-													if version >= 19.5 {
-														u.Path = path.Join(getAPIBasePath(), "server", "config", "parallelbackup")
-														exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{command: "set", parallelbackupenabled: parallelBackupEnabled})
-														if exitStatus != 0 {
-															exitStatus = 10001
-														}
-													} else {
-														exitStatus = 3
-													}
-												}
-
-												needToRestartFlag := false
-												restartMessageFlag := false
-												if results[8] != "" || results[9] != "" || results[10] != "" {
-													// for Claris FileMaker Server 21.0.1 or later
-													if version >= 21.0 {
-														var persistentCacheSettings []string
+											if results[6] != "" {
+												// for Claris FileMaker Server 19.3.2 or later
+												changes = append(changes, newSettingChange("server/config/authenticatedstream", cmdArgs[2:], params{command: "set", authenticatedstream: authenticatedStream}))
+											}
 
-														u.Path = path.Join(getAPIBasePath(), "server", "config", "persistentcache")
+											if results[7] != "" {
+												// for Claris FileMaker Server 19.5.1 or later
+												changes = append(changes, newSettingChange("server/config/parallelbackup", cmdArgs[2:], params{command: "set", parallelbackupenabled: parallelBackupEnabled}))
+											}
 
-														persistentCacheSettings, exitStatus, _ = getPersistentCacheConfigurations(u.String(), token, noPrintOptions)
-														if exitStatus == 0 {
-															if persistCacheEnabled == "" {
-																persistCacheEnabled = persistentCacheSettings[0]
-															} else if persistCacheEnabled != persistentCacheSettings[0] {
-																needToRestartFlag = true
-															}
+											needToRestartFlag := false
+											if results[8] != "" || results[9] != "" || results[10] != "" {
+												// for Claris FileMaker Server 21.0.1 or later
+												var persistentCacheSettings []string
+
+												u.Path = path.Join(getAPIBasePath(), "server", "config", "persistentcache")
+												persistentCacheSettings, exitStatus, _ = getPersistentCacheConfigurations(u.String(), token, noPrintOptions)
+												if exitStatus == 0 {
+													if persistCacheEnabled == "" {
+														persistCacheEnabled = persistentCacheSettings[0]
+													} else if persistCacheEnabled != persistentCacheSettings[0] {
+														needToRestartFlag = true
+													}
 
-															if syncPersistCache == "" {
-																syncPersistCache = persistentCacheSettings[1]
-															} else if syncPersistCache != persistentCacheSettings[1] && persistCacheEnabled == "true" {
-																// the value of PersistCacheEnabled must be true
-																needToRestartFlag = true
-															}
+													if syncPersistCache == "" {
+														syncPersistCache = persistentCacheSettings[1]
+													} else if syncPersistCache != persistentCacheSettings[1] && persistCacheEnabled == "true" {
+														needToRestartFlag = true
+													}
 
-															if databaseServerAutoRestart == "" {
-																databaseServerAutoRestart = persistentCacheSettings[2]
-															} else if databaseServerAutoRestart != persistentCacheSettings[2] && persistCacheEnabled == "true" {
-																// the value of PersistCacheEnabled must be true
-																needToRestartFlag = true
-															}
+													if databaseServerAutoRestart == "" {
+														databaseServerAutoRestart = persistentCacheSettings[2]
+													} else if databaseServerAutoRestart != persistentCacheSettings[2] && persistCacheEnabled == "true" {
+														needToRestartFlag = true
+													}
 
-															exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{command: "set", persistcacheenabled: persistCacheEnabled, syncpersistcache: syncPersistCache, databaseserverautorestart: databaseServerAutoRestart})
+													changes = append(changes, newSettingChange("server/config/persistentcache", cmdArgs[2:], params{command: "set", persistcacheenabled: persistCacheEnabled, syncpersistcache: syncPersistCache, databaseserverautorestart: databaseServerAutoRestart}))
+												} else {
+													exitStatus = 10001
+												}
+											}
 
-															if exitStatus == 0 {
-																for _, option := range printOptions {
-																	if (option == "persistcacheenabled" || option == "databaseserverautorestart") && needToRestartFlag {
-																		restartMessageFlag = true
-																		break
-																	}
-																}
-															}
-														}
+											if results[11] != "" {
+												// for Claris FileMaker Server 21.0.1 or later
+												changes = append(changes, newSettingChange("server/config/blocknewusers", cmdArgs[2:], params{command: "set", blocknewusersenabled: blockNewUsersEnabled}))
+											}
 
-														if exitStatus != 0 {
-															exitStatus = 10001
-														}
-													} else {
-														exitStatus = 3
-													}
-												}
+											if results[12] != "" {
+												// for Claris FileMaker Server 21.1.1 or later
+												changes = append(changes, newSettingChange("fmclients/httpstunneling", cmdArgs[2:], params{command: "set", enablehttpprotocolnetwork: enableHttpProtocolNetwork}))
+											}
 
-												if results[11] != "" {
-													// for Claris FileMaker Server 21.0.1 or later
-													if version >= 21.0 {
-														u.Path = path.Join(getAPIBasePath(), "server", "config", "blocknewusers")
-														exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{command: "set", blocknewusersenabled: blockNewUsersEnabled})
-														if exitStatus != 0 {
-															exitStatus = 10001
-														}
-													} else {
-														exitStatus = 3
-													}
-												}
+											if exitStatus == 0 {
+												exitStatus = applySettingChanges(c, baseURI, token, changes)
+											}
 
-												if results[12] != "" {
-													// for Claris FileMaker Server 21.1.1 or later
-													if version >= 21.1 {
-														u.Path = path.Join(getAPIBasePath(), "fmclients", "httpstunneling")
-														exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{command: "set", enablehttpprotocolnetwork: enableHttpProtocolNetwork})
-														if exitStatus != 0 {
-															exitStatus = 10001
-														}
-													} else {
-														exitStatus = 3
+											if exitStatus == 0 {
+												restartMessageFlag := false
+												for _, option := range printOptions {
+													if (option == "persistcacheenabled" || option == "databaseserverautorestart") && needToRestartFlag {
+														restartMessageFlag = true
+														break
 													}
 												}
 
@@ -2444,7 +3066,7 @@ func (c *cli) Run(args []string) int {
 								}
 							}
 
-							logout(baseURI, token)
+							c.logout(baseURI, token)
 						} else if detectHostUnreachable(exitStatus) {
 							exitStatus = 10502
 						}
@@ -2455,6 +3077,8 @@ func (c *cli) Run(args []string) int {
 			} else {
 				exitStatus = outputInvalidCommandErrorMessage(c)
 			}
+		case "shell":
+			exitStatus = runShell(c, baseURI, fqdn, username, password, params{identityFile: identityFile, credentialHelper: credentialHelper})
 		case "start":
 			if usingCloud {
 				exitStatus = 21
@@ -2462,7 +3086,7 @@ func (c *cli) Run(args []string) int {
 				if len(cmdArgs[1:]) > 0 {
 					switch strings.ToLower(cmdArgs[1]) {
 					case "server":
-						token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+						token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 						if token != "" && exitStatus == 0 && err == nil {
 							var running string
 							u.Path = path.Join(getAPIBasePath(), "server", "status")
@@ -2474,7 +3098,7 @@ func (c *cli) Run(args []string) int {
 								// start database server
 								exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{status: "RUNNING"})
 							}
-							logout(baseURI, token)
+							c.logout(baseURI, token)
 						} else if detectHostUnreachable(exitStatus) {
 							exitStatus = 10502
 						}
@@ -2486,46 +3110,55 @@ func (c *cli) Run(args []string) int {
 				}
 			}
 		case "status":
+			// STATUS without a type shows the status of the server
+			statusType := "server"
 			if len(cmdArgs[1:]) > 0 {
-				switch strings.ToLower(cmdArgs[1]) {
-				case "client":
-					token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
-					if token != "" && exitStatus == 0 && err == nil {
-						id := 0
-						if len(cmdArgs) >= 3 {
-							sid, err := strconv.Atoi(cmdArgs[2])
-							if err == nil {
-								id = sid
-							}
-						}
-						if id > 0 {
-							u.Path = path.Join(getAPIBasePath(), "clients")
-							exitStatus = listClients(u.String(), token, id)
+				statusType = strings.ToLower(cmdArgs[1])
+			}
+			switch statusType {
+			case "client":
+				token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+				if token != "" && exitStatus == 0 && err == nil {
+					id := 0
+					if len(cmdArgs) >= 3 {
+						sid, err := strconv.Atoi(cmdArgs[2])
+						if err == nil {
+							id = sid
 						}
-						logout(baseURI, token)
-					} else if detectHostUnreachable(exitStatus) {
-						exitStatus = 10502
 					}
-				case "file":
-					token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
-					if token != "" && exitStatus == 0 && err == nil {
-						if len(cmdArgs[2:]) > 0 {
-							u.Path = path.Join(getAPIBasePath(), "databases")
-							idList, _, _ := getDatabases(u.String(), token, cmdArgs[2:], "", false)
-							if len(idList) > 0 {
-								exitStatus = listFiles(c, u.String(), token, idList)
-							}
-						} else {
-							exitStatus = 10001
+					if id > 0 {
+						u.Path = path.Join(getAPIBasePath(), "clients")
+						exitStatus = listClients(c, u.String(), token, id, clientListOptions{outputOptions: output.resolve(baseURI, usingCloud)})
+					}
+					c.logout(baseURI, token)
+				} else if detectHostUnreachable(exitStatus) {
+					exitStatus = 10502
+				}
+			case "file":
+				token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+				if token != "" && exitStatus == 0 && err == nil {
+					if len(cmdArgs[2:]) > 0 {
+						u.Path = path.Join(getAPIBasePath(), "databases")
+						idList, _, _ := getDatabases(u.String(), token, cmdArgs[2:], "", false)
+						if len(idList) > 0 {
+							exitStatus = listFiles(c, u.String(), token, idList, output)
 						}
-						logout(baseURI, token)
-					} else if detectHostUnreachable(exitStatus) {
-						exitStatus = 10502
+					} else {
+						exitStatus = 10001
 					}
-				default:
-					exitStatus = outputInvalidCommandErrorMessage(c)
+					c.logout(baseURI, token)
+				} else if detectHostUnreachable(exitStatus) {
+					exitStatus = 10502
 				}
-			} else {
+			case "server":
+				token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
+				if token != "" && exitStatus == 0 && err == nil {
+					exitStatus = showServerStatus(c, baseURI, token, usingCloud)
+					c.logout(baseURI, token)
+				} else if detectHostUnreachable(exitStatus) {
+					exitStatus = 10502
+				}
+			default:
 				exitStatus = outputInvalidCommandErrorMessage(c)
 			}
 		case "stop":
@@ -2537,15 +3170,14 @@ func (c *cli) Run(args []string) int {
 					if yesFlag {
 						res = "y"
 					} else {
-						r := bufio.NewReader(os.Stdin)
 						fmt.Fprint(c.outStream, "fmcsadmin: really stop server? (y, n) ")
-						input, _ := r.ReadString('\n')
+						input, _ := c.input().ReadString('\n')
 						res = strings.ToLower(strings.TrimSpace(input))
 					}
 					if res == "y" {
 						switch strings.ToLower(cmdArgs[1]) {
 						case "server":
-							token, exitStatus, err = login(baseURI, username, password, params{retry: retry, identityFile: identityFile})
+							token, exitStatus, err = c.login(baseURI, username, password, params{retry: retry, identityFile: identityFile, credentialHelper: credentialHelper})
 							if token != "" && exitStatus == 0 && err == nil {
 								message = "Stopping FileMaker Database Engine..."
 								// message = "FileMaker データベースエンジンの停止中..."
@@ -2554,9 +3186,11 @@ func (c *cli) Run(args []string) int {
 								}
 								exitStatus, _ = stopDatabaseServer(u, token, message, graceTime)
 								if exitStatus == 0 {
-									exitStatus, _ = waitStoppingServer(u, token)
+									if exitStatus, err = waitStoppingServer(u, token); err == errWaitTimeout {
+										exitStatus = c.timedOut("the server to stop")
+									}
 								}
-								logout(baseURI, token)
+								c.logout(baseURI, token)
 							} else if detectHostUnreachable(exitStatus) {
 								exitStatus = 10502
 							}
@@ -2583,7 +3217,11 @@ func (c *cli) Run(args []string) int {
 		}
 	}
 
-	if exitStatus != 0 && exitStatus != 23 && exitStatus != 248 && exitStatus != 249 {
+	if exitStatus == 10001 && c.failure == 0 && lastAPIError != nil && lastAPIError.code == 0 && lastAPIError.statusCode >= 400 {
+		// the server failed without a FileMaker error code, such as 502 Bad Gateway
+		c.failure = exitStatusHTTPError
+	}
+	if exitStatus != 0 && exitStatus != 23 && exitStatus != 248 && exitStatus != 249 && !c.session.expired() {
 		outputErrorMessage(exitStatus, c)
 	}
 
@@ -2610,6 +3248,35 @@ func getFlags(args []string, cFlags commandOptions) ([]string, commandOptions, e
 	clientID := -1
 	graceTime := 90
 	identityFile := ""
+	passwordFile := ""
+	passwordStdin := false
+	credentialHelper := ""
+	fileFilter := ""
+	userFilter := ""
+	appVersionFilter := ""
+	ipFilter := ""
+	sortKey := ""
+	where := ""
+	at := ""
+	repeat := ""
+	until := ""
+	scheduleID := 0
+	dryRun := false
+	humanFlag := false
+	timeZone := ""
+	clientsWarning := -1
+	clientsCritical := -1
+	certWarning := -1
+	certCritical := -1
+	policyFile := ""
+	maintenanceFlag := false
+	debugFlag := false
+	traceFlag := false
+	logFile := ""
+	retries := 0
+	retryMaxWait := ""
+	retryNonIdempotentFlag := false
+	exitCodeMode := ""
 
 	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	flags.Usage = func() {}
@@ -2645,6 +3312,35 @@ func getFlags(args []string, cFlags commandOptions) ([]string, commandOptions, e
 	flags.IntVar(&graceTime, "t", 90, "Specify time in seconds before client is forced to disconnect.")
 	flags.IntVar(&graceTime, "gracetime", 90, "Specify time in seconds before client is forced to disconnect.")
 	flags.StringVar(&identityFile, "i", "", "Specify a private key file for FileMaker Admin API PKI Authentication.")
+	flags.StringVar(&passwordFile, "password-file", "", "Read the password to authenticate with the server from a file.")
+	flags.BoolVar(&passwordStdin, "password-stdin", false, "Read the password to authenticate with the server from the standard input.")
+	flags.StringVar(&credentialHelper, "credential-helper", "", "Specify an external command to retrieve credentials.")
+	flags.StringVar(&fileFilter, "file", "", "Filter clients by a hosted file name.")
+	flags.StringVar(&userFilter, "user", "", "Filter clients by a user name.")
+	flags.StringVar(&appVersionFilter, "app-version", "", "Filter clients by an application version.")
+	flags.StringVar(&ipFilter, "ip", "", "Filter clients by an IP address or CIDR block.")
+	flags.StringVar(&sortKey, "sort", "", "Sort clients by connectTime, duration or user.")
+	flags.StringVar(&where, "where", "", "Specify conditions to select clients.")
+	flags.StringVar(&at, "at", "", "Specify the time to send a message.")
+	flags.StringVar(&repeat, "repeat", "", "Specify the interval to send a message repeatedly.")
+	flags.StringVar(&until, "until", "", "Specify the time to stop sending a message repeatedly.")
+	flags.IntVar(&scheduleID, "schedule", 0, "Specify a backup schedule ID.")
+	flags.BoolVar(&dryRun, "dry-run", false, "Show what would be done without changing anything.")
+	flags.BoolVar(&humanFlag, "human", false, "Print sizes, durations and times in a human-readable format.")
+	flags.StringVar(&timeZone, "tz", "", "Specify the time zone for timestamps (Local, UTC, Server or an IANA name).")
+	flags.IntVar(&clientsWarning, "clients-warning", -1, "Specify the number of clients for a WARNING state.")
+	flags.IntVar(&clientsCritical, "clients-critical", -1, "Specify the number of clients for a CRITICAL state.")
+	flags.IntVar(&certWarning, "cert-warning", -1, "Specify the days before certificate expiry for a WARNING state.")
+	flags.IntVar(&certCritical, "cert-critical", -1, "Specify the days before certificate expiry for a CRITICAL state.")
+	flags.StringVar(&policyFile, "policy", "", "Specify a policy file to lint the server configuration against.")
+	flags.BoolVar(&maintenanceFlag, "maintenance", false, "Skip policy rules that only apply outside maintenance.")
+	flags.BoolVar(&debugFlag, "debug", false, "Log the method, path, status code and latency of Admin API requests.")
+	flags.BoolVar(&traceFlag, "trace", false, "Log Admin API requests with redacted request and response bodies.")
+	flags.StringVar(&logFile, "log-file", "", "Write the log of Admin API requests to the file.")
+	flags.IntVar(&retries, "retries", 0, "Retry Admin API requests failing with a transient error up to N times.")
+	flags.StringVar(&retryMaxWait, "retry-max-wait", "", "Maximum wait between retries (e.g. 30s).")
+	flags.BoolVar(&retryNonIdempotentFlag, "retry-non-idempotent", false, "Also retry requests that change the server.")
+	flags.StringVar(&exitCodeMode, "exit-code-mode", "", "Exit status mode: legacy or mapped.")
 
 	buf := &bytes.Buffer{}
 	flags.SetOutput(buf)
@@ -2699,13 +3395,86 @@ func getFlags(args []string, cFlags commandOptions) ([]string, commandOptions, e
 	if cFlags.identityFile == "" {
 		cFlags.identityFile = identityFile
 	}
-
-	cmdArgs = flags.Args()
-
-	var resultArgs []string
-	var subCmdArgs []string
-
-	if len(cmdArgs) > 0 {
+	if cFlags.passwordFile == "" {
+		cFlags.passwordFile = passwordFile
+	}
+	cFlags.passwordStdin = cFlags.passwordStdin || passwordStdin
+	if cFlags.credentialHelper == "" {
+		cFlags.credentialHelper = credentialHelper
+	}
+	if cFlags.fileFilter == "" {
+		cFlags.fileFilter = fileFilter
+	}
+	if cFlags.userFilter == "" {
+		cFlags.userFilter = userFilter
+	}
+	if cFlags.appVersionFilter == "" {
+		cFlags.appVersionFilter = appVersionFilter
+	}
+	if cFlags.ipFilter == "" {
+		cFlags.ipFilter = ipFilter
+	}
+	if cFlags.sortKey == "" {
+		cFlags.sortKey = sortKey
+	}
+	if cFlags.where == "" {
+		cFlags.where = where
+	}
+	if cFlags.at == "" {
+		cFlags.at = at
+	}
+	if cFlags.repeat == "" {
+		cFlags.repeat = repeat
+	}
+	if cFlags.until == "" {
+		cFlags.until = until
+	}
+	if cFlags.scheduleID == 0 {
+		cFlags.scheduleID = scheduleID
+	}
+	cFlags.dryRun = cFlags.dryRun || dryRun
+	cFlags.humanFlag = cFlags.humanFlag || humanFlag
+	if cFlags.timeZone == "" {
+		cFlags.timeZone = timeZone
+	}
+	if cFlags.clientsWarning == -1 {
+		cFlags.clientsWarning = clientsWarning
+	}
+	if cFlags.clientsCritical == -1 {
+		cFlags.clientsCritical = clientsCritical
+	}
+	if cFlags.certWarning == -1 {
+		cFlags.certWarning = certWarning
+	}
+	if cFlags.certCritical == -1 {
+		cFlags.certCritical = certCritical
+	}
+	if cFlags.policyFile == "" {
+		cFlags.policyFile = policyFile
+	}
+	cFlags.maintenanceFlag = cFlags.maintenanceFlag || maintenanceFlag
+	cFlags.debugFlag = cFlags.debugFlag || debugFlag
+	cFlags.traceFlag = cFlags.traceFlag || traceFlag
+	if cFlags.logFile == "" {
+		cFlags.logFile = logFile
+	}
+	if cFlags.retries == 0 {
+		cFlags.retries = retries
+	}
+	if cFlags.retryMaxWait == "" {
+		cFlags.retryMaxWait = retryMaxWait
+	}
+	cFlags.retryNonIdempotentFlag = cFlags.retryNonIdempotentFlag || retryNonIdempotentFlag
+	if cFlags.exitCodeMode == "" {
+		cFlags.exitCodeMode = exitCodeMode
+	}
+
+	cmdArgs = flags.Args()
+
+	var resultArgs []string
+	var subCmdArgs []string
+
+	if len(cmdArgs) > 0 {
 		resultArgs = append(resultArgs, cmdArgs[0])
 	}
 
@@ -2757,11 +3526,394 @@ func getFlags(args []string, cFlags commandOptions) ([]string, commandOptions, e
 		if cFlags.identityFile == "" {
 			cFlags.identityFile = subCommandOptions.identityFile
 		}
+		if cFlags.passwordFile == "" {
+			cFlags.passwordFile = subCommandOptions.passwordFile
+		}
+		cFlags.passwordStdin = cFlags.passwordStdin || subCommandOptions.passwordStdin
+		if cFlags.credentialHelper == "" {
+			cFlags.credentialHelper = subCommandOptions.credentialHelper
+		}
+		if cFlags.fileFilter == "" {
+			cFlags.fileFilter = subCommandOptions.fileFilter
+		}
+		if cFlags.userFilter == "" {
+			cFlags.userFilter = subCommandOptions.userFilter
+		}
+		if cFlags.appVersionFilter == "" {
+			cFlags.appVersionFilter = subCommandOptions.appVersionFilter
+		}
+		if cFlags.ipFilter == "" {
+			cFlags.ipFilter = subCommandOptions.ipFilter
+		}
+		if cFlags.sortKey == "" {
+			cFlags.sortKey = subCommandOptions.sortKey
+		}
+		if cFlags.where == "" {
+			cFlags.where = subCommandOptions.where
+		}
+		if cFlags.at == "" {
+			cFlags.at = subCommandOptions.at
+		}
+		if cFlags.repeat == "" {
+			cFlags.repeat = subCommandOptions.repeat
+		}
+		if cFlags.until == "" {
+			cFlags.until = subCommandOptions.until
+		}
+		if cFlags.scheduleID == 0 {
+			cFlags.scheduleID = subCommandOptions.scheduleID
+		}
+		cFlags.dryRun = cFlags.dryRun || subCommandOptions.dryRun
+		cFlags.humanFlag = cFlags.humanFlag || subCommandOptions.humanFlag
+		if cFlags.timeZone == "" {
+			cFlags.timeZone = subCommandOptions.timeZone
+		}
+		if cFlags.clientsWarning == -1 {
+			cFlags.clientsWarning = subCommandOptions.clientsWarning
+		}
+		if cFlags.clientsCritical == -1 {
+			cFlags.clientsCritical = subCommandOptions.clientsCritical
+		}
+		if cFlags.certWarning == -1 {
+			cFlags.certWarning = subCommandOptions.certWarning
+		}
+		if cFlags.certCritical == -1 {
+			cFlags.certCritical = subCommandOptions.certCritical
+		}
+		if cFlags.policyFile == "" {
+			cFlags.policyFile = subCommandOptions.policyFile
+		}
+		cFlags.maintenanceFlag = cFlags.maintenanceFlag || subCommandOptions.maintenanceFlag
+		cFlags.debugFlag = cFlags.debugFlag || subCommandOptions.debugFlag
+		cFlags.traceFlag = cFlags.traceFlag || subCommandOptions.traceFlag
+		if cFlags.logFile == "" {
+			cFlags.logFile = subCommandOptions.logFile
+		}
+		if cFlags.retries == 0 {
+			cFlags.retries = subCommandOptions.retries
+		}
+		if cFlags.retryMaxWait == "" {
+			cFlags.retryMaxWait = subCommandOptions.retryMaxWait
+		}
+		cFlags.retryNonIdempotentFlag = cFlags.retryNonIdempotentFlag || subCommandOptions.retryNonIdempotentFlag
+		if cFlags.exitCodeMode == "" {
+			cFlags.exitCodeMode = subCommandOptions.exitCodeMode
+		}
 	}
 
 	return resultArgs, cFlags, nil
 }
 
+// settingRange is the range of values allowed for a numeric setting.
+type settingRange struct {
+	// minVersion is the first version of Claris FileMaker Server using the range
+	minVersion   string
+	min          int
+	max          int
+	defaultValue int
+}
+
+// settingRule defines the values allowed for a setting of SERVERCONFIG and SERVERPREFS.
+type settingRule struct {
+	// names are the names of the setting, the first one is printed in help output
+	names []string
+	// ranges are the ranges for numeric settings, ordered by minVersion
+	ranges []settingRange
+	// requires is the boolean setting that must be true when the setting is true
+	requires string
+}
+
+// settingRules is used to validate settings before changing them, and to print
+// their ranges by the GET and SET commands and in help output.
+var settingRules = []settingRule{
+	{names: []string{"cachesize"}, ranges: []settingRange{{min: 64, max: 1048576, defaultValue: 512}}},
+	{names: []string{"hostedfiles", "maxfiles"}, ranges: []settingRange{{min: 1, max: 125, defaultValue: 125}, {minVersion: "20.1", min: 1, max: 256, defaultValue: 256}}},
+	{names: []string{"proconnections", "maxguests"}, ranges: []settingRange{{min: 0, max: 2000, defaultValue: 250}}},
+	{names: []string{"scriptsessions", "allowpsos"}, ranges: []settingRange{{min: 0, max: 500, defaultValue: 100}}},
+	{names: []string{"authenticatedstream"}, ranges: []settingRange{{min: 1, max: 2, defaultValue: 1}}},
+	{names: []string{"syncpersistcache"}, requires: "persistcacheenabled"},
+	{names: []string{"databaseserverautorestart"}, requires: "persistcacheenabled"},
+}
+
+func getSettingRule(name string) (settingRule, bool) {
+	for _, rule := range settingRules {
+		for _, n := range rule.names {
+			if n == strings.ToLower(name) {
+				return rule, true
+			}
+		}
+	}
+
+	return settingRule{}, false
+}
+
+// getRange returns the range of the setting for the version of the server.
+func (r settingRule) getRange(version serverVersion) (settingRange, bool) {
+	var result settingRange
+	found := false
+	for _, sr := range r.ranges {
+		if sr.minVersion == "" || version.atLeast(sr.minVersion) {
+			result = sr
+			found = true
+		}
+	}
+
+	return result, found
+}
+
+// getSettingRangeString returns the default value and the range of the setting
+// (e.g. "[default: 512, range: 64-1048576]").
+func getSettingRangeString(name string, version serverVersion) string {
+	rule, _ := getSettingRule(name)
+	sr, ok := rule.getRange(version)
+	if !ok {
+		return ""
+	}
+
+	return "[default: " + strconv.Itoa(sr.defaultValue) + ", range: " + strconv.Itoa(sr.min) + "-" + strconv.Itoa(sr.max) + "]"
+}
+
+// getSettingRulesHelpText returns the allowed values of the settings for help output.
+func getSettingRulesHelpText() string {
+	var b strings.Builder
+	for _, rule := range settingRules {
+		name := strings.ToUpper(rule.names[0])
+		var descriptions []string
+		for i, sr := range rule.ranges {
+			description := strconv.Itoa(sr.min) + "-" + strconv.Itoa(sr.max) + " (default: " + strconv.Itoa(sr.defaultValue) + ")"
+			if i+1 < len(rule.ranges) {
+				description = description + " before FileMaker Server " + rule.ranges[i+1].minVersion
+			} else if sr.minVersion != "" {
+				description = description + " on FileMaker Server " + sr.minVersion + " or later"
+			}
+			descriptions = append(descriptions, description)
+		}
+		if rule.requires != "" {
+			descriptions = append(descriptions, "TRUE requires "+strings.ToUpper(rule.requires)+"=TRUE")
+		}
+		for i, description := range descriptions {
+			if i == 0 && len(name) < 17 {
+				b.WriteString(fmt.Sprintf("      %-17s%s\n", name, description))
+			} else {
+				if i == 0 {
+					b.WriteString("      " + name + "\n")
+				}
+				b.WriteString(fmt.Sprintf("      %-17s%s\n", "", description))
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func isSettingValueTrue(value string) bool {
+	value = strings.ToLower(value)
+	if value == "true" {
+		return true
+	}
+	n, err := strconv.Atoi(value)
+
+	return err == nil && n != 0
+}
+
+// validateServerSettings checks the values of NAME=VALUE arguments and the
+// dependencies between them before changing any setting. current holds the
+// values of boolean settings on the server that the arguments depend on.
+func validateServerSettings(c *cli, args []string, version serverVersion, versionString string, current map[string]string) int {
+	exitStatus := 0
+	values := map[string]string{}
+	for _, arg := range args {
+		name, value, found := strings.Cut(arg, "=")
+		if !found {
+			continue
+		}
+		name = strings.ToLower(name)
+		rule, ok := getSettingRule(name)
+		if !ok {
+			values[name] = value
+			continue
+		}
+		values[rule.names[0]] = value
+
+		sr, ok := rule.getRange(version)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < sr.min || n > sr.max {
+			allowed := "allowed: " + strconv.Itoa(sr.min) + "-" + strconv.Itoa(sr.max)
+			if len(rule.ranges) > 1 && versionString != "" {
+				allowed = allowed + " on FileMaker Server " + versionString
+			}
+			fmt.Fprintln(c.outStream, "fmcsadmin: invalid value for "+strings.ToUpper(name)+": "+value+" ("+allowed+")")
+			exitStatus = 10001
+		}
+	}
+
+	for _, rule := range settingRules {
+		value, ok := values[rule.names[0]]
+		if rule.requires == "" || !ok || !isSettingValueTrue(value) {
+			continue
+		}
+		if requiredValue, ok := values[rule.requires]; ok {
+			if !isSettingValueTrue(requiredValue) {
+				fmt.Fprintln(c.outStream, "fmcsadmin: "+strings.ToUpper(rule.names[0])+"=true requires "+strings.ToUpper(rule.requires)+"=true")
+				exitStatus = 10001
+			}
+		} else if currentValue, ok := current[rule.requires]; ok && currentValue != "true" {
+			fmt.Fprintln(c.outStream, "fmcsadmin: "+strings.ToUpper(rule.names[0])+"=true requires "+strings.ToUpper(rule.requires)+"=true (current: "+currentValue+")")
+			exitStatus = 10001
+		}
+	}
+
+	return exitStatus
+}
+
+// settingChange is a PATCH request changing the settings of an endpoint of the Admin API.
+type settingChange struct {
+	// endpoint is the path of the endpoint relative to the base path (e.g. "server/config/general")
+	endpoint string
+	// names are the names of the settings to change, used in the rollback report
+	names  []string
+	params params
+	// snapshot is the params restoring the settings before the change
+	snapshot params
+}
+
+// getSettingEndpoint returns the endpoint of the Admin API for the setting of SERVERCONFIG or SERVERPREFS.
+func getSettingEndpoint(name string) string {
+	switch strings.ToLower(name) {
+	case "cachesize", "hostedfiles", "maxfiles", "proconnections", "maxguests", "scriptsessions", "allowpsos", "startuprestorationenabled", "onlyopenlastopeneddatabases":
+		return "server/config/general"
+	case "securefilesonly", "requiresecuredb":
+		return "server/config/security"
+	case "authenticatedstream":
+		return "server/config/authenticatedstream"
+	case "parallelbackupenabled":
+		return "server/config/parallelbackup"
+	case "persistcacheenabled", "syncpersistcache", "databaseserverautorestart":
+		return "server/config/persistentcache"
+	case "blocknewusersenabled":
+		return "server/config/blocknewusers"
+	case "enablehttpprotocolnetwork":
+		return "fmclients/httpstunneling"
+	}
+	if setting, ok := getConnectorSetting(name); ok {
+		return setting.endpoint
+	}
+
+	return ""
+}
+
+// newSettingChange returns the change of the endpoint for the NAME=VALUE arguments.
+func newSettingChange(endpoint string, args []string, p params) settingChange {
+	var names []string
+	for _, arg := range args {
+		name, _, _ := strings.Cut(arg, "=")
+		if getSettingEndpoint(name) == endpoint {
+			names = append(names, strings.ToUpper(name))
+		}
+	}
+
+	return settingChange{endpoint: endpoint, names: names, params: p}
+}
+
+// getSettingSnapshot returns the params restoring the current settings of the endpoint.
+func getSettingSnapshot(baseURI string, token string, endpoint string) (params, int) {
+	u, _ := url.Parse(baseURI)
+	u.Path = path.Join(getAPIBasePath(), endpoint)
+	p := params{command: "set"}
+	exitStatus := 0
+
+	switch endpoint {
+	case "server/config/general":
+		var settings []int
+		settings, exitStatus = getServerGeneralConfigurations(u.String(), token, []string{})
+		if exitStatus == 0 && len(settings) > 4 {
+			p.cachesize = settings[0]
+			p.maxfiles = settings[1]
+			p.maxproconnections = settings[2]
+			p.maxpsos = settings[3]
+			p.startuprestorationbuiltin = settings[4] != -1
+			p.startuprestorationenabled = settings[4] == 1
+			if len(settings) > 5 {
+				// for Claris FileMaker Server 21.1.1 or later
+				p.onlyopenlastopeneddatabases = strconv.FormatBool(settings[5] == 1)
+			}
+		}
+	case "server/config/authenticatedstream":
+		p.authenticatedstream, exitStatus, _ = getAuthenticatedStreamSetting(u.String(), token, []string{})
+	case "server/config/persistentcache":
+		var settings []string
+		settings, exitStatus, _ = getPersistentCacheConfigurations(u.String(), token, []string{})
+		if exitStatus == 0 && len(settings) > 2 {
+			p.persistcacheenabled = settings[0]
+			p.syncpersistcache = settings[1]
+			p.databaseserverautorestart = settings[2]
+		}
+	default:
+		var enabled bool
+		var err error
+		enabled, exitStatus, err = getServerSettingAsBool(u.String(), token, []string{})
+		if exitStatus == 0 && err != nil {
+			exitStatus = 3
+		}
+		value := strconv.FormatBool(enabled)
+		switch endpoint {
+		case "server/config/security":
+			p.requiresecuredb = value
+		case "server/config/parallelbackup":
+			p.parallelbackupenabled = value
+		case "server/config/blocknewusers":
+			p.blocknewusersenabled = value
+		case "fmclients/httpstunneling":
+			p.enablehttpprotocolnetwork = value
+		case "fmdapi/config", "odata/config", "webdirect/config":
+			p.enabled = value
+		}
+	}
+
+	return p, exitStatus
+}
+
+// applySettingChanges takes a snapshot of all the endpoints and sends the changes
+// in order. When a change fails, the changes already applied are reverted to the
+// snapshot in reverse order and the result of the rollback is printed.
+func applySettingChanges(c *cli, baseURI string, token string, changes []settingChange) int {
+	for i := range changes {
+		snapshot, exitStatus := getSettingSnapshot(baseURI, token, changes[i].endpoint)
+		if exitStatus != 0 {
+			fmt.Fprintln(c.outStream, "fmcsadmin: could not retrieve the current value of "+strings.Join(changes[i].names, ", ")+"; no settings were changed")
+			return exitStatus
+		}
+		changes[i].snapshot = snapshot
+	}
+
+	u, _ := url.Parse(baseURI)
+	for i, change := range changes {
+		u.Path = path.Join(getAPIBasePath(), change.endpoint)
+		exitStatus, _, _ := sendRequest("PATCH", u.String(), token, change.params)
+		if exitStatus != 0 {
+			fmt.Fprintln(c.outStream, "fmcsadmin: failed to change "+strings.Join(change.names, ", ")+" (Error: "+strconv.Itoa(exitStatus)+")")
+			if i == 0 {
+				fmt.Fprintln(c.outStream, "No settings were changed.")
+			}
+			for j := i - 1; j >= 0; j-- {
+				u.Path = path.Join(getAPIBasePath(), changes[j].endpoint)
+				result, _, _ := sendRequest("PATCH", u.String(), token, changes[j].snapshot)
+				if result == 0 {
+					fmt.Fprintln(c.outStream, "Rolled back: "+strings.Join(changes[j].names, ", "))
+				} else {
+					fmt.Fprintln(c.outStream, "Failed to roll back: "+strings.Join(changes[j].names, ", ")+" (Error: "+strconv.Itoa(result)+")")
+				}
+			}
+			return exitStatus
+		}
+	}
+
+	return 0
+}
+
 func parseServerConfigurationSettings(str []string) ([]string, int) {
 	exitStatus := 0
 	var results []string
@@ -2984,6 +4136,35 @@ func parseWebConfigurationSettings(str []string) ([]string, int) {
 	return results, exitStatus
 }
 
+func parseConnectorConfigurationSettings(str []string) ([]string, int) {
+	exitStatus := 0
+	results := make([]string, len(connectorSettings))
+
+	for i := 0; i < len(str); i++ {
+		val := strings.ToLower(str[i])
+		name, _, _ := strings.Cut(val, "=")
+		found := false
+		for j, setting := range connectorSettings {
+			if name != setting.name {
+				continue
+			}
+			found = true
+			if val == setting.name+"=" {
+				exitStatus = 10001
+			} else if val == setting.name+"=true" || (regexp.MustCompile(setting.name+`=([+|-])?(\d)+`).Match([]byte(val)) && val != setting.name+"=0" && val != setting.name+"=+0" && val != setting.name+"=-0") {
+				results[j] = "true"
+			} else {
+				results[j] = "false"
+			}
+		}
+		if !found {
+			exitStatus = 10001
+		}
+	}
+
+	return results, exitStatus
+}
+
 func outputInvalidCommandParameterErrorMessage(c *cli) int {
 	exitStatus := 23
 	fmt.Fprintln(c.outStream, "Error: 10007 (Requested object does not exist)")
@@ -3023,7 +4204,24 @@ func getAPIBasePath() string {
 	return path
 }
 
-func getUsernameAndPassword(username string, password string, product int) (string, string) {
+// input returns the buffered reader of the input stream.
+func (c *cli) input() *bufio.Reader {
+	if c.reader == nil {
+		in := c.inStream
+		if in == nil {
+			in = os.Stdin
+		}
+		c.reader = bufio.NewReader(in)
+	}
+
+	return c.reader
+}
+
+// getUsernameAndPassword fills in the username and the password from the
+// environment variables or the prompts, and reports whether the password was typed in.
+func getUsernameAndPassword(username string, password string, product int) (string, string, bool) {
+	prompted := false
+
 	if len(username) == 0 {
 		if product == 1 {
 			username = os.Getenv("FMS_USERNAME")
@@ -3049,1606 +4247,4543 @@ func getUsernameAndPassword(username string, password string, product int) (stri
 			bytePassword, _ := term.ReadPassword(int(syscall.Stdin))
 			password = string(bytePassword)
 			fmt.Printf("\n")
+			prompted = true
 		}
 	}
 
-	return username, password
+	return username, password, prompted
 }
 
-func login(baseURI string, user string, pass string, p params) (string, int, error) {
-	var body []byte
+func readPassword(c *cli, passwordFile string, passwordStdin bool) (string, int) {
+	var data []byte
 	var err error
-	token := ""
-	exitStatus := 0
-
-	if regexp.MustCompile(`https://(.*)\.account\.filemaker-cloud\.com/`).Match([]byte(baseURI)) {
-		// for Claris FileMaker Cloud
-		exitStatus = 21
-		err = fmt.Errorf("%s", "Not Supported")
-	} else {
-		// for Claris FileMaker Server
-		username := user
-		password := pass
-		if p.identityFile == "" {
-			username, password = getUsernameAndPassword(user, pass, 1)
-		}
-
-		u, _ := url.Parse(baseURI)
-		u.Path = path.Join(getAPIBasePath(), "user", "auth")
 
-		output := output{}
-		if p.identityFile == "" {
-			body, _, err = callURL("POST", u.String(), "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil)
-		} else {
-			var jwtToken string
-			jwtToken, exitStatus, err = getJWTToken(p.identityFile)
-			if err != nil || exitStatus > 0 {
-				return token, exitStatus, err
+	if len(passwordFile) > 0 {
+		data, err = os.ReadFile(passwordFile)
+		if err != nil {
+			if os.IsPermission(err) {
+				fmt.Fprintln(c.outStream, "Cannot read password file")
+				return "", 20402
 			}
-			body, _, _ = callURL("POST", u.String(), "PKI "+jwtToken, nil)
+			fmt.Fprintln(c.outStream, "Password file "+filepath.Clean(passwordFile)+" does not exist.")
+			return "", 20405
 		}
+	} else if passwordStdin {
+		input, err := c.input().ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", 20408
+		}
+		data = []byte(input)
+	}
 
-		/* for debugging */
-		//fmt.Println(bytes.NewBuffer([]byte(body)))
+	// use the first line only
+	password := strings.SplitN(string(data), "\n", 2)[0]
+	password = strings.TrimRight(password, "\r")
+	if len(password) == 0 {
+		fmt.Fprintln(c.outStream, "Password is not specified.")
+		return "", 10001
+	}
 
-		if err != nil {
-			exitStatus = 10502
-			return token, exitStatus, err
-		}
+	return password, 0
+}
 
-		err = json.Unmarshal(body, &output)
-		if err != nil {
-			return token, exitStatus, err
-		}
+func getStoredCredentials(out io.Writer, baseURI string, username string, password string, helper string) (string, string, string, int) {
+	if len(password) > 0 {
+		return username, password, "", 0
+	}
 
-		code := ""
-		for i := 0; i < len(output.Messages); i++ {
-			if reflect.ValueOf(output.Messages[i].Code).IsValid() {
-				code = output.Messages[i].Code
-				break
-			}
-		}
-		if code == "0" {
-			token = output.Response.Token
-		} else {
-			if p.retry > 0 {
-				fmt.Println("fmcsadmin: Permission denied, please try again.")
-				token, exitStatus, err = login(baseURI, user, pass, params{retry: p.retry - 1, identityFile: p.identityFile})
-				if err != nil {
-					exitStatus = 10502
-					return token, exitStatus, err
-				}
-			} else {
-				fmt.Println("fmcsadmin: Permission denied.")
-				exitStatus = 9
+	// external credential helper
+	helper = getCredentialHelper(helper)
+	if len(helper) > 0 {
+		credentials, err := runCredentialHelper(helper, "get", baseURI, username, "")
+		if err == nil && len(credentials["password"]) > 0 {
+			if len(username) == 0 {
+				username = credentials["username"]
 			}
+			return username, credentials["password"], "helper", 0
 		}
 	}
 
-	return token, exitStatus, err
-}
+	// encrypted local credential file
+	u, err := url.Parse(baseURI)
+	if err != nil {
+		return username, password, "", 0
+	}
+	store, err := loadCredentialStore(getCredentialFilePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return username, password, "", 0
+	} else if err != nil {
+		fmt.Fprintln(out, "fmcsadmin: Could not read the credential file: "+err.Error())
+		return username, password, "", 9
+	}
+	if _, ok := store.Entries[u.Host]; !ok {
+		return username, password, "", 0
+	}
+	storedUsername, storedPassword, err := store.get(u.Host, getMasterPassphrase(false))
+	if err != nil {
+		fmt.Fprintln(out, "fmcsadmin: Invalid master passphrase.")
+		return username, password, "", 9
+	}
+	if len(username) > 0 && username != storedUsername {
+		return username, password, "", 0
+	}
 
-func getJWTToken(filePath string) (string, int, error) {
-	// for public key infrastructure (PKI) authentication
-	var err error
-	var pkey *rsa.PrivateKey
-	passphrase := ""
+	return storedUsername, storedPassword, "store", 0
+}
 
-	keyData, keyFormat, exitStatus := detectPrivateKeyFormat(filePath, "")
-	if exitStatus != 0 && exitStatus != 212 {
-		return "", exitStatus, nil
+func getCredentialHelper(helper string) string {
+	if len(helper) == 0 {
+		helper = os.Getenv("FMCSADMIN_CREDENTIAL_HELPER")
 	}
 
-	if exitStatus == 212 {
-		fmt.Print("Enter passphrase: ")
-		bytePassphrase, _ := term.ReadPassword(int(syscall.Stdin))
-		passphrase = string(bytePassphrase)
-		fmt.Printf("\n")
-		keyData, _, exitStatus = detectPrivateKeyFormat(filePath, passphrase)
-	} else if keyFormat == "PRIVATE KEY" || keyFormat == "EC PRIVATE KEY" || keyFormat == "EC PARAMETERS" {
-		exitStatus = 21
-		return "", exitStatus, nil
+	return strings.TrimSpace(helper)
+}
+
+// runCredentialHelper runs an external credential helper in the same way as
+// the credential.helper of git. The operation ("get", "store" or "erase") is
+// appended to the command line, and the attributes are exchanged as
+// "key=value" lines via the standard input and output.
+func runCredentialHelper(helper string, operation string, baseURI string, username string, password string) (map[string]string, error) {
+	credentials := map[string]string{}
+	if len(helper) == 0 {
+		return credentials, errors.New("credential helper is not specified")
 	}
 
-	// Name of public key on FileMaker Server Admin Console
-	keyName := strings.Replace(filepath.Base(filePath), filepath.Ext(filePath), "", 1)
+	u, err := url.Parse(baseURI)
+	if err != nil {
+		return credentials, err
+	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
-		"iss": strings.Replace(keyName, "_", " ", -1),
-		"aud": "fmsadminapi",
-		"exp": time.Now().Add(time.Minute * 15).Unix(),
-	})
+	input := "protocol=" + u.Scheme + "\n" + "host=" + u.Host + "\n"
+	if len(username) > 0 {
+		input = input + "username=" + username + "\n"
+	}
+	if len(password) > 0 {
+		input = input + "password=" + password + "\n"
+	}
+	input = input + "\n"
 
-	if passphrase == "" {
-		pkey, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(keyData))
-		if err != nil {
-			exitStatus = 20408
-			return "", exitStatus, err
-		}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", helper+" "+operation)
 	} else {
-		pkey, err = jwt.ParseRSAPrivateKeyFromPEMWithPassword([]byte(keyData), passphrase)
-		if err != nil {
-			exitStatus = 20408
-			return "", exitStatus, err
-		}
+		cmd = exec.Command("sh", "-c", helper+" "+operation)
 	}
-	tokenString, _ := jwtToken.SignedString(pkey)
-
-	return tokenString, exitStatus, err
-}
-
-func detectPrivateKeyFormat(filePath string, keyFilePass string) ([]byte, string, int) {
-	keyType := ""
-	exitStatus := 0
-
-	_, err := os.Stat(filePath)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
 	if err != nil {
-		exitStatus = 20405
+		return credentials, err
 	}
 
-	keyData, err := os.ReadFile(filePath)
-	if err != nil {
-		if os.IsPermission(err) {
-			exitStatus = 20402
-		} else {
-			exitStatus = 20405
-		}
-	} else {
-		block, _ := pem.Decode(keyData)
-		if block == nil {
-			exitStatus = 20408
-		} else {
-			buf := block.Bytes
-			if x509.IsEncryptedPEMBlock(block) {
-				buf, err = x509.DecryptPEMBlock(block, []byte(keyFilePass))
-				if err != nil {
-					if err == x509.IncorrectPasswordError {
-						exitStatus = 212
-					}
-				}
-			}
+	return parseCredentialHelperOutput(string(out)), nil
+}
 
-			if exitStatus == 0 {
-				switch block.Type {
-				case "RSA PRIVATE KEY":
-					_, err = x509.ParsePKCS1PrivateKey(buf)
-					if err != nil {
-						exitStatus = 20408
-					}
-				case "PRIVATE KEY":
-					_, err := x509.ParsePKCS8PrivateKey(buf)
-					if err != nil {
-						exitStatus = 20408
-					}
-				case "EC PRIVATE KEY":
-					_, err := x509.ParseECPrivateKey(buf)
-					if err != nil {
-						exitStatus = 20408
-					}
-				default:
-					exitStatus = 20408
-				}
-				keyType = block.Type
-			}
+func parseCredentialHelperOutput(output string) map[string]string {
+	credentials := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(line) == 0 {
+			break
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 {
+			credentials[kv[0]] = kv[1]
 		}
 	}
 
-	return keyData, keyType, exitStatus
+	return credentials
 }
 
-func logout(baseURI string, token string) {
-	u, _ := url.Parse(baseURI)
-	u.Path = path.Join(getAPIBasePath(), "user", "auth", token)
-	sendRequest("DELETE", u.String(), token, params{})
+type credentialStore struct {
+	Version    int                        `json:"version"`
+	Salt       string                     `json:"salt"`
+	Iterations int                        `json:"iterations"`
+	Entries    map[string]credentialEntry `json:"entries"`
 }
 
-func getResultCode(v interface{}) int {
-	var resultCode string
-
-	err := scan.ScanTree(v, "/messages[0]/code", &resultCode)
-	if err != nil {
-		return -1
-	}
-	code, _ := strconv.Atoi(resultCode)
-
-	return code
+type credentialEntry struct {
+	Nonce string `json:"nonce"`
+	Data  string `json:"data"`
 }
 
-func listClients(urlString string, token string, id int) int {
-	usingCloud := false
-	if regexp.MustCompile(`https://(.*)\.account\.filemaker-cloud\.com/`).Match([]byte(urlString)) {
-		usingCloud = true
-	}
+type storedCredential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
 
-	body, _, err := callURL("GET", urlString, token, nil)
-	if err != nil {
-		fmt.Println(err.Error())
-		return -1
+func getCredentialFilePath() string {
+	filePath := os.Getenv("FMCSADMIN_CREDENTIAL_FILE")
+	if len(filePath) > 0 {
+		return filePath
 	}
 
-	var v interface{}
-	body2 := []byte(body)
-	err = json.Unmarshal(body2, &v)
+	dir, err := os.UserConfigDir()
 	if err != nil {
-		fmt.Println(err.Error())
+		dir, _ = os.UserHomeDir()
 	}
 
-	result := getResultCode(v)
-	if result == 1701 {
-		// when fmserverd is stopping
-		return 10502
-	}
+	return filepath.Join(dir, "fmcsadmin", "credentials.json")
+}
 
-	mode := "NORMAL"
-	if id > -1 {
-		mode = "DETAIL"
+func getMasterPassphrase(confirm bool) string {
+	passphrase := os.Getenv("FMCSADMIN_MASTER_PASSPHRASE")
+	if len(passphrase) > 0 {
+		return passphrase
 	}
 
-	var count int
-	var c []string
-	var s string
-	var s1 string
-	var userName string
-	var computerName string
-	var extPriv string
-	var ipAddress string
-	var macAddress string
-	var connectTime string
-	var connectDuration string
-	var appVersion string
-	var appLanguage string
-	//var teamLicensed string
-	var fileName string
-	var accountName string
-	var privsetName string
-	//var b bool
-	var data [][]string
-	var sID int
-
-	_ = scan.ScanTree(v, "/response/clients", &c)
-	count = len(c)
-
-	if mode == "NORMAL" {
-		if count > 0 {
-			for i := 0; i < count; i++ {
-				_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/status", &s)
-				if s == "NORMAL" {
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/id", &s1)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/userName", &userName)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/computerName", &computerName)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/extpriv", &extPriv)
-					data = append(data, []string{s1, userName, computerName, extPriv})
-				}
-			}
-
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"Client ID", "User Name", "Computer Name", "Ext Privilege"})
-			table.SetAutoWrapText(false)
-			table.SetAutoFormatHeaders(false)
-			for _, v := range data {
-				table.Append(v)
-			}
-			table.Render()
-		}
-	} else {
-		if count > 0 {
-			for i := 0; i < count; i++ {
-				_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/status", &s)
-				if s == "NORMAL" {
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/id", &s1)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/userName", &userName)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/computerName", &computerName)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/extpriv", &extPriv)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/ipaddress", &ipAddress)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/macaddress", &macAddress)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/connectTime", &connectTime)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/connectDuration", &connectDuration)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/appVersion", &appVersion)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/appLanguage", &appLanguage)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/guestFiles[0]/filename", &fileName)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/guestFiles[0]/accountName", &accountName)
-					_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(i)+"]/guestFiles[0]/privsetName", &privsetName)
-
-					connectTime = getDateTimeStringOfCurrentTimeZone(connectTime, "2006/01/02 15:04:05", usingCloud)
-					if regexp.MustCompile(`(.*)\.fmp12`).Match([]byte(fileName)) {
-						rep := regexp.MustCompile(`(.*)\.fmp12`)
-						fileName = rep.ReplaceAllString(fileName, "$1")
-					}
-
-					data = append(data, []string{s1, userName, computerName, extPriv, ipAddress, macAddress, connectTime, connectDuration, appVersion, appLanguage, fileName, accountName, privsetName})
-				}
-			}
-
-			sID, _ = strconv.Atoi(s1)
-			if id == sID || id == 0 {
-				table := tablewriter.NewWriter(os.Stdout)
-				table.SetHeader([]string{"Client ID", "User Name", "Computer Name", "Ext Privilege", "IP Address", "MAC Address", "Connect Time", "Duration", "App Version", "App Language", "File Name", "Account Name", "Privilege Set"})
-				table.SetAutoWrapText(false)
-				table.SetAutoFormatHeaders(false)
-				for _, v := range data {
-					table.Append(v)
-				}
-				table.Render()
-			}
+	fmt.Print("master passphrase: ")
+	bytePassphrase, _ := term.ReadPassword(int(syscall.Stdin))
+	fmt.Printf("\n")
+	if confirm {
+		fmt.Print("confirm master passphrase: ")
+		byteConfirmation, _ := term.ReadPassword(int(syscall.Stdin))
+		fmt.Printf("\n")
+		if string(bytePassphrase) != string(byteConfirmation) {
+			return ""
 		}
 	}
 
-	return 0
+	return string(bytePassphrase)
 }
 
-func listFiles(c *cli, url string, token string, idList []int) int {
-	body, _, err := callURL("GET", url, token, nil)
-	if err != nil {
-		fmt.Println(err.Error())
-		return -1
+func newCredentialStore() (*credentialStore, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
 	}
 
-	/* for debugging */
-	//fmt.Println(bytes.NewBuffer([]byte(body)))
+	return &credentialStore{
+		Version:    1,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Iterations: 200000,
+		Entries:    map[string]credentialEntry{},
+	}, nil
+}
 
-	var v interface{}
-	body2 := []byte(body)
-	err = json.Unmarshal(body2, &v)
+func loadCredentialStore(filePath string) (*credentialStore, error) {
+	data, err := os.ReadFile(filePath)
 	if err != nil {
-		fmt.Println(err.Error())
+		return nil, err
 	}
 
-	result := getResultCode(v)
-	if result == 1701 {
-		// when fmserverd is stopping
-		return 10502
+	store := &credentialStore{}
+	err = json.Unmarshal(data, store)
+	if err != nil {
+		return nil, err
 	}
-
-	mode := "NORMAL"
-	if (len(idList) == 1 && idList[0] > -1) || len(idList) > 1 {
-		mode = "DETAIL"
+	if store.Entries == nil {
+		store.Entries = map[string]credentialEntry{}
 	}
 
-	var totalDbCount int
-	var count []string
-	var s string
-	var s1 string
-	var fileName string
-	var status string
-	var extPriv string
-	var isEncrypted string
-	var num1 int
-	var num2 int
-	var b bool
-	var data [][]string
-
-	_ = scan.ScanTree(v, "/response/totalDBCount", &totalDbCount)
-
-	if mode == "NORMAL" {
-		for i := 0; i < totalDbCount; i++ {
-			_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/status", &s)
-			if s == "NORMAL" {
-				_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/folder", &s)
-				fmt.Fprint(c.outStream, s)
-				_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/filename", &s)
-				fmt.Fprintln(c.outStream, s)
-			}
-		}
-	} else {
-		for i := 0; i < totalDbCount; i++ {
-			_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/id", &s1)
-			for j := 0; j < len(idList); j++ {
-				if s1 == strconv.Itoa(idList[j]) || idList[j] == 0 {
-					_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/filename", &fileName)
-					_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/clients", &num1)
-					_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/size", &num2)
-					_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/status", &status)
-
-					if status == "CLOSED" {
-						extPriv = "-"
-					} else {
-						extPriv = ""
-						_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/enabledExtPrivileges", &count)
-						for j := 0; j < len(count); j++ {
-							_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/enabledExtPrivileges["+strconv.Itoa(j)+"]", &s)
-							if extPriv == "" {
-								extPriv = s
-							} else {
-								extPriv = extPriv + " " + s
-							}
-						}
-					}
-
-					isEncrypted = ""
-					_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/isEncrypted", &b)
-					if b {
-						isEncrypted = "Yes"
-					} else {
-						isEncrypted = "No"
-					}
+	return store, nil
+}
 
-					data = append(data, []string{s1, fileName, strconv.Itoa(num1), strconv.Itoa(num2), status[:1] + strings.ToLower(status[1:]), extPriv, isEncrypted})
-				}
-			}
-		}
+func saveCredentialStore(filePath string, store *credentialStore) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
 
-		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"ID", "File", "Clients", "Size", "Status", "Enabled Extended Privileges", "Encrypted"})
-		table.SetAutoWrapText(false)
-		table.SetAutoFormatHeaders(false)
-		for _, v := range data {
-			table.Append(v)
-		}
-		table.Render()
+	err = os.MkdirAll(filepath.Dir(filePath), 0700)
+	if err != nil {
+		return err
 	}
 
-	return 0
+	return os.WriteFile(filePath, data, 0600)
 }
 
-func getServerVersion(url string, token string) float64 {
-	versionString, err := getServerVersionString(url, token)
+func (store *credentialStore) cipher(passphrase string) (cipher.AEAD, error) {
+	salt, err := base64.StdEncoding.DecodeString(store.Salt)
 	if err != nil {
-		return 0.0
+		return nil, err
 	}
 
-	version, err := getServerVersionAsFloat(versionString)
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(passphrase), salt, store.Iterations, 32, sha256.New))
 	if err != nil {
-		return 0.0
+		return nil, err
 	}
 
-	return version
+	return cipher.NewGCM(block)
 }
 
-func getServerVersionString(urlString string, token string) (string, error) {
-	body, _, err := callURL("GET", urlString, token, nil)
+func (store *credentialStore) set(host string, username string, password string, passphrase string) error {
+	aead, err := store.cipher(passphrase)
 	if err != nil {
-		return "0.0.0", err
+		return err
 	}
 
-	/* for debugging */
-	//fmt.Println(bytes.NewBuffer([]byte(body)))
-
-	var v interface{}
-	body2 := []byte(body)
-	err = json.Unmarshal(body2, &v)
-	if err != nil {
-		return "0.0.0", err
+	// the passphrase has to match the one used for the existing entries
+	for existingHost := range store.Entries {
+		if _, _, err = store.get(existingHost, passphrase); err != nil {
+			return err
+		}
+		break
 	}
 
-	var versionString string
-	err = scan.ScanTree(v, "/response/ServerVersion", &versionString)
+	plaintext, err := json.Marshal(storedCredential{username, password})
 	if err != nil {
-		return "0.0.0", err
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+	store.Entries[host] = credentialEntry{
+		Nonce: base64.StdEncoding.EncodeToString(nonce),
+		Data:  base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, []byte(host))),
 	}
 
-	return versionString, err
+	return nil
 }
 
-func getServerVersionAsFloat(versionString string) (float64, error) {
-	version, err := strconv.ParseFloat(strings.Join(strings.Split(versionString, ".")[0:2], "."), 64)
-	if err != nil {
-		return 0.0, err
+func (store *credentialStore) get(host string, passphrase string) (string, string, error) {
+	entry, ok := store.Entries[host]
+	if !ok {
+		return "", "", errors.New("credential not found")
 	}
 
-	return version, err
-}
-
-func listPlugins(url string, token string) int {
-	body, _, err := callURL("GET", url, token, nil)
+	aead, err := store.cipher(passphrase)
 	if err != nil {
-		fmt.Println(err.Error())
-		return -1
+		return "", "", err
 	}
-
-	/* for debugging */
-	//fmt.Println(bytes.NewBuffer([]byte(body)))
-
-	var v interface{}
-	body2 := []byte(body)
-	err = json.Unmarshal(body2, &v)
+	nonce, err := base64.StdEncoding.DecodeString(entry.Nonce)
 	if err != nil {
-		fmt.Println(err.Error())
+		return "", "", err
 	}
-
-	result := getResultCode(v)
-	if result == 1701 {
-		// when fmserverd is stopping
-		return 10502
+	data, err := base64.StdEncoding.DecodeString(entry.Data)
+	if err != nil {
+		return "", "", err
+	}
+	plaintext, err := aead.Open(nil, nonce, data, []byte(host))
+	if err != nil {
+		return "", "", err
 	}
 
-	var count int
-	var c []string
-	var s1 string
-	var pluginName string
-	var fileName string
-	var enabled bool
-	var status string
-	var data [][]string
+	credential := storedCredential{}
+	err = json.Unmarshal(plaintext, &credential)
 
-	_ = scan.ScanTree(v, "/response/plugins", &c)
-	count = len(c)
+	return credential.Username, credential.Password, err
+}
 
-	if count > 0 {
-		for i := 0; i < count; i++ {
-			_ = scan.ScanTree(v, "/response/plugins["+strconv.Itoa(i)+"]/id", &s1)
-			_ = scan.ScanTree(v, "/response/plugins["+strconv.Itoa(i)+"]/pluginName", &pluginName)
-			_ = scan.ScanTree(v, "/response/plugins["+strconv.Itoa(i)+"]/filename", &fileName)
-			_ = scan.ScanTree(v, "/response/plugins["+strconv.Itoa(i)+"]/enabled", &enabled)
-			status = "Disabled"
-			if enabled {
-				status = "Enabled"
+func login(out io.Writer, baseURI string, user string, pass string, p params) (string, int, error) {
+	var body []byte
+	var err error
+	token := ""
+	exitStatus := 0
+
+	if isCloudURI(baseURI) {
+		// for Claris FileMaker Cloud
+		token, exitStatus, err = loginWithClarisID(out, baseURI, user, pass, p)
+	} else {
+		// for Claris FileMaker Server
+		username := user
+		password := pass
+		source := ""
+		prompted := false
+		if p.identityFile == "" {
+			username, password, source, exitStatus = getStoredCredentials(out, baseURI, user, pass, p.credentialHelper)
+			if exitStatus != 0 {
+				return token, exitStatus, fmt.Errorf("%s", getErrorDescription(exitStatus))
 			}
-			data = append(data, []string{s1, pluginName, fileName, status})
+			username, password, prompted = getUsernameAndPassword(username, password, 1)
 		}
 
-		if len(data) > 0 {
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetAutoWrapText(false)
-			table.SetAutoFormatHeaders(false)
-			for _, v := range data {
-				table.SetHeader([]string{"ID", "Name", "File", "Status"})
-				table.Append(v)
+		u, _ := url.Parse(baseURI)
+		u.Path = path.Join(getAPIBasePath(), "user", "auth")
+
+		output := output{}
+		if p.identityFile == "" {
+			body, _, err = callURL("POST", u.String(), "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil)
+		} else {
+			var jwtToken string
+			jwtToken, exitStatus, err = getJWTToken(p.identityFile)
+			if err != nil || exitStatus > 0 {
+				return token, exitStatus, err
 			}
-			table.Render()
+			body, _, _ = callURL("POST", u.String(), "PKI "+jwtToken, nil)
 		}
-	}
 
-	return 0
-}
+		if err != nil {
+			exitStatus = 10502
+			return token, exitStatus, err
+		}
 
-func listSchedules(urlString string, token string, id int) int {
-	usingCloud := false
-	if regexp.MustCompile(`https://(.*)\.account\.filemaker-cloud\.com/`).Match([]byte(urlString)) {
-		usingCloud = true
-	}
+		err = json.Unmarshal(body, &output)
+		if err != nil {
+			return token, exitStatus, err
+		}
 
-	body, _, err := callURL("GET", urlString, token, nil)
-	if err != nil {
-		fmt.Println(err.Error())
-		return -1
-	}
-
-	/* for debugging */
-	//fmt.Println(bytes.NewBuffer([]byte(body)))
-
-	var v interface{}
-	body2 := []byte(body)
-	err = json.Unmarshal(body2, &v)
-	if err != nil {
-		fmt.Println(err.Error())
-	}
-
-	result := getResultCode(v)
-	if result == 1701 {
-		// when fmserverd is stopping
-		return 10502
-	}
-
-	var count int
-	var c []string
-	var s1 string
-	var sID int
-	var name string
-	var taskType string
-	var backupType string
-	var filemakerScriptType string
-	var messageType string
-	var scriptSequenceType string
-	var systemScriptType string
-	var verifyType string
-	var lastRun string
-	var nextRun string
-	var enabled bool
-	var status string
-	var data [][]string
-
-	_ = scan.ScanTree(v, "/response/schedules", &c)
-	count = len(c)
-
-	if count > 0 {
-		for i := 0; i < count; i++ {
-			_ = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/id", &s1)
-			_ = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/name", &name)
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/backupType/resourceType", &backupType)
-			if err != nil {
-				backupType = ""
-			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/filemakerScriptType/resource", &filemakerScriptType)
-			if err != nil {
-				filemakerScriptType = ""
-			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/messageType/resourceType", &messageType)
-			if err != nil {
-				messageType = ""
-			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/scriptSequenceType/resource", &scriptSequenceType)
-			if err != nil {
-				scriptSequenceType = ""
-			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/systemScriptType/osScript", &systemScriptType)
-			if err != nil {
-				systemScriptType = ""
-			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/verifyType/resourceType", &verifyType)
-			if err != nil {
-				verifyType = ""
-			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/lastRun", &lastRun)
-			if err != nil {
-				lastRun = ""
+		code := ""
+		for i := 0; i < len(output.Messages); i++ {
+			if reflect.ValueOf(output.Messages[i].Code).IsValid() {
+				code = output.Messages[i].Code
+				break
 			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/nextRun", &nextRun)
-			if err != nil {
-				nextRun = ""
+		}
+		if code == "0" {
+			token = output.Response.Token
+			if prompted {
+				// let the credential helper remember the credentials typed in
+				_, _ = runCredentialHelper(getCredentialHelper(p.credentialHelper), "store", baseURI, username, password)
 			}
-			_ = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/enabled", &enabled)
-			if !enabled {
-				nextRun = "Disabled"
+		} else if source != "" {
+			// don't retry with the same stored credentials
+			if source == "helper" {
+				_, _ = runCredentialHelper(getCredentialHelper(p.credentialHelper), "erase", baseURI, username, password)
 			}
-			_ = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/status", &status)
-
-			sID, _ = strconv.Atoi(s1)
-			if id == sID || id == 0 {
-				if status == "IDLE" || status == "RUNNING" {
-					if lastRun == "" || lastRun == "0000-00-00T00:00:00" {
-						status = ""
-					} else {
-						status = "OK"
-					}
-				}
-				if backupType != "" {
-					taskType = "Backup"
-				} else if filemakerScriptType != "" {
-					taskType = "FileMaker Script"
-				} else if messageType != "" {
-					taskType = "Message"
-				} else if scriptSequenceType != "" {
-					taskType = "Script Sequence"
-				} else if systemScriptType != "" {
-					taskType = "System Script"
-				} else if verifyType != "" {
-					taskType = "Verify"
+			fmt.Fprintln(out, "fmcsadmin: Permission denied.")
+			exitStatus = 9
+		} else {
+			if p.retry > 0 {
+				fmt.Fprintln(out, "fmcsadmin: Permission denied, please try again.")
+				token, exitStatus, err = login(out, baseURI, user, pass, params{retry: p.retry - 1, identityFile: p.identityFile, credentialHelper: p.credentialHelper})
+				if err != nil {
+					exitStatus = 10502
+					return token, exitStatus, err
 				}
-				lastRun = getDateTimeStringOfCurrentTimeZone(lastRun, "2006/01/02 15:04", usingCloud)
-				nextRun = getDateTimeStringOfCurrentTimeZone(nextRun, "2006/01/02 15:04", usingCloud)
-				data = append(data, []string{s1, name, taskType, lastRun, nextRun, status})
-			}
-		}
-
-		if len(data) > 0 {
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetAutoWrapText(false)
-			table.SetAutoFormatHeaders(false)
-			for _, v := range data {
-				table.SetHeader([]string{"ID", "Name", "Type", "Last Completed", "Next Run", "Status"})
-				table.Append(v)
+			} else {
+				fmt.Fprintln(out, "fmcsadmin: Permission denied.")
+				exitStatus = 9
 			}
-			table.Render()
-		} else {
-			return 10600
 		}
 	}
 
-	return 0
+	return token, exitStatus, err
 }
 
-func getScheduleName(url string, token string, id int) string {
-	body, _, err := callURL("GET", url, token, nil)
-	if err != nil {
-		//fmt.Println(err.Error())
-		return ""
-	}
+// clarisIDEndpoint, clarisIDClientID and clarisIDPoolID are the Amazon Cognito
+// user pool of Claris ID. FMCSADMIN_CLARIS_ID_ENDPOINT, FMCSADMIN_CLARIS_ID_CLIENT_ID
+// and FMCSADMIN_CLARIS_ID_POOL_ID override them (e.g. for an identity provider
+// for testing). The password is verified with USER_SRP_AUTH and is not sent.
+const clarisIDEndpoint = "https://cognito-idp.us-west-2.amazonaws.com/"
+const clarisIDClientID = "4l9rvl4mv5es1eep1qe97cautn"
+const clarisIDPoolID = "us-west-2_NqkuZcXQY"
+
+type clarisIDAuthInfo struct {
+	AuthFlow       string            `json:"AuthFlow"`
+	ClientID       string            `json:"ClientId"`
+	AuthParameters map[string]string `json:"AuthParameters"`
+}
 
-	/* for debugging */
-	//fmt.Println(bytes.NewBuffer([]byte(body)))
+type clarisIDChallengeInfo struct {
+	ChallengeName      string            `json:"ChallengeName"`
+	ClientID           string            `json:"ClientId"`
+	ChallengeResponses map[string]string `json:"ChallengeResponses"`
+	Session            string            `json:"Session,omitempty"`
+}
+
+type clarisIDAuthResponse struct {
+	AuthenticationResult struct {
+		IDToken      string `json:"IdToken"`
+		RefreshToken string `json:"RefreshToken"`
+	} `json:"AuthenticationResult"`
+	ChallengeName       string            `json:"ChallengeName"`
+	ChallengeParameters map[string]string `json:"ChallengeParameters"`
+	Session             string            `json:"Session"`
+	Type                string            `json:"__type"`
+	Message             string            `json:"message"`
+}
 
-	var v interface{}
-	body2 := []byte(body)
-	err = json.Unmarshal(body2, &v)
+// isCloudURI reports whether the URI is of a Claris FileMaker Cloud host.
+func isCloudURI(uri string) bool {
+	u, err := url.Parse(uri)
 	if err != nil {
-		//fmt.Println(err.Error())
-		return ""
+		return false
 	}
 
-	var s1 string
-	var sID int
-	var name string
+	return u.Scheme == "https" && strings.HasSuffix(strings.ToLower(u.Hostname()), ".account.filemaker-cloud.com")
+}
 
-	_ = scan.ScanTree(v, "/response/schedule/id", &s1)
-	_ = scan.ScanTree(v, "/response/schedule/name", &name)
-	sID, _ = strconv.Atoi(s1)
-	if id == sID || id == 0 {
-		return name
+// getClarisIDProvider returns the endpoint, the app client ID and the user pool ID of Claris ID.
+func getClarisIDProvider() (string, string, string) {
+	endpoint := os.Getenv("FMCSADMIN_CLARIS_ID_ENDPOINT")
+	if endpoint == "" {
+		endpoint = clarisIDEndpoint
+	}
+	clientID := os.Getenv("FMCSADMIN_CLARIS_ID_CLIENT_ID")
+	if clientID == "" {
+		clientID = clarisIDClientID
+	}
+	poolID := os.Getenv("FMCSADMIN_CLARIS_ID_POOL_ID")
+	if poolID == "" {
+		poolID = clarisIDPoolID
 	}
 
-	return ""
+	return endpoint, clientID, poolID
 }
 
-func sendMessages(u *url.URL, token string, message string, cmdArgs []string, clientID int) int {
-	var exitStatus int
+// callClarisID calls the action ("InitiateAuth" or "RespondToAuthChallenge") of the
+// Claris ID user pool and returns the response and its HTTP status code.
+func callClarisID(action string, request interface{}) (clarisIDAuthResponse, int, int, error) {
+	var response clarisIDAuthResponse
+	endpoint, _, _ := getClarisIDProvider()
 
-	args := []string{""}
-	if len(cmdArgs[1:]) > 0 {
-		args = cmdArgs[1:]
+	requestBody, _ := json.Marshal(request)
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(requestBody))
+	if err != nil {
+		return response, 0, 10502, err
 	}
-	u.Path = path.Join(getAPIBasePath(), "clients")
-	idList := getClients(u.String(), token, args)
-	if len(idList) > 0 {
-		for i := 0; i < len(idList); i++ {
-			if clientID == -1 || clientID == idList[i] {
-				u.Path = path.Join(getAPIBasePath(), "clients", strconv.Itoa(idList[i]), "message")
-				exitStatus = sendMessage(u.String(), token, message)
-			}
-			if clientID > 0 {
-				break
-			}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AWSCognitoIdentityProviderService."+action)
+
+	start := time.Now()
+	client := &http.Client{Timeout: 60 * time.Second}
+	res, err := client.Do(req)
+	statusCode := 0
+	var body []byte
+	if err == nil {
+		statusCode = res.StatusCode
+		body, err = io.ReadAll(res.Body)
+		res.Body.Close()
+	}
+	apiLogger.logRequest(req, requestBody, statusCode, time.Since(start), body, err)
+	if err != nil {
+		return response, statusCode, 10502, err
+	}
+
+	if err = json.Unmarshal(body, &response); err != nil {
+		return response, statusCode, 3, err
+	}
+
+	return response, statusCode, 0, nil
+}
+
+// getClarisIDTokensFromResponse returns the ID token and the refresh token in the response of the Claris ID user pool.
+func getClarisIDTokensFromResponse(response clarisIDAuthResponse, statusCode int) (string, string, int, error) {
+	if response.AuthenticationResult.IDToken == "" {
+		if response.ChallengeName != "" {
+			// e.g. multi-factor authentication
+			return "", "", 9, fmt.Errorf("unsupported challenge: %s", response.ChallengeName)
 		}
-	} else {
-		exitStatus = 10904
+		if statusCode >= 500 {
+			return "", "", 10502, fmt.Errorf("%s", response.Message)
+		}
+		return "", "", 9, fmt.Errorf("%s", response.Message)
 	}
 
-	return exitStatus
+	return response.AuthenticationResult.IDToken, response.AuthenticationResult.RefreshToken, 0, nil
 }
 
-func sendMessage(url string, token string, message string) int {
-	d := messageInfo{
-		message,
+// getClarisIDTokens calls InitiateAuth of the Claris ID user pool with authFlow
+// (e.g. "REFRESH_TOKEN_AUTH") and returns the ID token and the refresh token.
+// The refresh token is empty for REFRESH_TOKEN_AUTH.
+func getClarisIDTokens(authFlow string, authParameters map[string]string) (string, string, int, error) {
+	_, clientID, _ := getClarisIDProvider()
+	response, statusCode, exitStatus, err := callClarisID("InitiateAuth", clarisIDAuthInfo{AuthFlow: authFlow, ClientID: clientID, AuthParameters: authParameters})
+	if exitStatus != 0 {
+		return "", "", exitStatus, err
 	}
-	jsonStr, _ := json.Marshal(d)
-	body, _, err := callURL("POST", url, token, bytes.NewBuffer([]byte(jsonStr)))
+
+	return getClarisIDTokensFromResponse(response, statusCode)
+}
+
+// getClarisIDTokensWithPassword signs in to the Claris ID user pool with USER_SRP_AUTH,
+// answering the PASSWORD_VERIFIER challenge, and returns the ID token and the refresh token.
+func getClarisIDTokensWithPassword(username string, password string) (string, string, int, error) {
+	_, clientID, poolID := getClarisIDProvider()
+	srp, err := newCognitoSRP(poolID)
 	if err != nil {
-		fmt.Println(err.Error())
+		return "", "", 3, err
 	}
 
-	/* for debugging */
-	//fmt.Println(bytes.NewBuffer([]byte(body)))
+	response, statusCode, exitStatus, err := callClarisID("InitiateAuth", clarisIDAuthInfo{AuthFlow: "USER_SRP_AUTH", ClientID: clientID, AuthParameters: map[string]string{"USERNAME": username, "SRP_A": srp.bigA.Text(16)}})
+	if exitStatus != 0 {
+		return "", "", exitStatus, err
+	}
+	if response.ChallengeName != "PASSWORD_VERIFIER" {
+		return getClarisIDTokensFromResponse(response, statusCode)
+	}
 
-	code := -1
-	output := output{}
-	err = json.Unmarshal(body, &output)
+	challengeResponses, err := srp.getPasswordClaim(response.ChallengeParameters, password, timeNow())
 	if err != nil {
-		fmt.Println(err.Error())
-	} else {
-		for i := 0; i < len(output.Messages); i++ {
-			if reflect.ValueOf(output.Messages[i].Code).IsValid() {
-				code, _ = strconv.Atoi(output.Messages[i].Code)
-				break
-			}
-		}
+		return "", "", 9, err
+	}
+	response, statusCode, exitStatus, err = callClarisID("RespondToAuthChallenge", clarisIDChallengeInfo{ChallengeName: "PASSWORD_VERIFIER", ClientID: clientID, ChallengeResponses: challengeResponses, Session: response.Session})
+	if exitStatus != 0 {
+		return "", "", exitStatus, err
 	}
 
-	return code
+	return getClarisIDTokensFromResponse(response, statusCode)
 }
 
-func getDatabases(url string, token string, arg []string, status string, fullPath bool) ([]int, []string, []string) {
-	var fileName string
-	var folderName string
-	var idList []int
-	var nameList []string
-	var hintList []string
-	var id int
+// cognitoSRPN and cognitoSRPG are the group parameters of the Secure Remote Password
+// protocol of Amazon Cognito user pools (the 3072-bit MODP group of RFC 3526).
+var cognitoSRPN, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B139B22514A08798E3404DDEF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7EDEE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3DC2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F83655D23DCA3AD961C62F356208552BB9ED529077096966D670C354E4ABC9804F1746C08CA18217C32905E462E36CE3BE39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9DE2BCBF6955817183995497CEA956AE515D2261898FA051015728E5A8AAAC42DAD33170D04507A33A85521ABDF1CBA64ECFB850458DBEF0A8AEA71575D060C7DB3970F85A6E1E4C7ABF5AE8CDB0933D71E8C94E04A25619DCEE3D2261AD2EE6BF12FFA06D98A0864D87602733EC86A64521F2B18177B200CBBE117577A615D6C770988C0BAD946E208E24FA074E5AB3143DB5BFCE0FD108E4B82D120A93AD2CAFFFFFFFFFFFFFFFF", 16)
+var cognitoSRPG = big.NewInt(2)
+
+// cognitoSRP is the client of the Secure Remote Password protocol of Amazon Cognito user pools.
+type cognitoSRP struct {
+	// poolName is the part of the user pool ID after the region (e.g. "NqkuZcXQY")
+	poolName string
+	a        *big.Int
+	bigA     *big.Int
+}
 
-	body, _, err := callURL("GET", url, token, nil)
+func newCognitoSRP(poolID string) (*cognitoSRP, error) {
+	a, err := rand.Int(rand.Reader, cognitoSRPN)
 	if err != nil {
-		fmt.Println(err.Error())
-		return idList, nameList, hintList
+		return nil, err
 	}
+	_, poolName, _ := strings.Cut(poolID, "_")
 
-	var v interface{}
-	err = json.Unmarshal(body, &v)
-	if err != nil {
-		fmt.Println(err.Error())
+	return &cognitoSRP{poolName: poolName, a: a, bigA: new(big.Int).Exp(cognitoSRPG, a, cognitoSRPN)}, nil
+}
+
+// cognitoPad returns the bytes of n hashed by Cognito, which have a leading zero byte when the high bit is set.
+func cognitoPad(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) == 0 || b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
 	}
 
-	// for debugging
-	/*
-		fmt.Println(url)
-		fmt.Println(bytes.NewBuffer([]byte(body)))
-	*/
+	return b
+}
 
-	var totalDbCount int
-	var fileStatus string
-	var s1 string
-	var s2 string
-	var fileID string
-	var decryptHint string
+// cognitoHash returns the SHA-256 hash of the data as an integer.
+func cognitoHash(data ...[]byte) *big.Int {
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
 
-	_ = scan.ScanTree(v, "/response/totalDBCount", &totalDbCount)
-	for i := 0; i < totalDbCount; i++ {
-		for j := 0; j < len(arg)+1; j++ {
-			if j == len(arg) && j > 0 {
-				break
-			}
+	return new(big.Int).SetBytes(h.Sum(nil))
+}
 
-			fileName = ""
-			folderName = ""
-			if len(arg) > 0 {
-				fileName = arg[j]
-				if strings.Contains(fileName, string(os.PathSeparator)) {
-					folderName = fileName
-				}
-			}
+// getPasswordClaim returns the responses to the PASSWORD_VERIFIER challenge, which
+// prove the password with the salt, SRP_B and SECRET_BLOCK of the challenge.
+func (s *cognitoSRP) getPasswordClaim(challenge map[string]string, password string, now time.Time) (map[string]string, error) {
+	userID := challenge["USER_ID_FOR_SRP"]
+	salt, ok1 := new(big.Int).SetString(challenge["SALT"], 16)
+	bigB, ok2 := new(big.Int).SetString(challenge["SRP_B"], 16)
+	secretBlock, err := base64.StdEncoding.DecodeString(challenge["SECRET_BLOCK"])
+	if !ok1 || !ok2 || err != nil || new(big.Int).Mod(bigB, cognitoSRPN).Sign() == 0 {
+		return nil, errors.New("invalid PASSWORD_VERIFIER challenge")
+	}
+
+	u := cognitoHash(cognitoPad(s.bigA), cognitoPad(bigB))
+	if u.Sign() == 0 {
+		return nil, errors.New("invalid PASSWORD_VERIFIER challenge")
+	}
+	k := cognitoHash(cognitoPad(cognitoSRPN), cognitoPad(cognitoSRPG))
+	userHash := sha256.Sum256([]byte(s.poolName + userID + ":" + password))
+	x := cognitoHash(cognitoPad(salt), userHash[:])
+
+	// S = (B - k * g^x) ^ (a + u * x) mod N
+	base := new(big.Int).Mul(k, new(big.Int).Exp(cognitoSRPG, x, cognitoSRPN))
+	base.Sub(bigB, base).Mod(base, cognitoSRPN)
+	exponent := new(big.Int).Add(s.a, new(big.Int).Mul(u, x))
+	bigS := new(big.Int).Exp(base, exponent, cognitoSRPN)
+
+	key := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, cognitoPad(bigS), cognitoPad(u), []byte("Caldera Derived Key")), key); err != nil {
+		return nil, err
+	}
+
+	timestamp := now.UTC().Format("Mon Jan 2 15:04:05 UTC 2006")
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s.poolName + userID))
+	mac.Write(secretBlock)
+	mac.Write([]byte(timestamp))
+
+	return map[string]string{
+		"USERNAME":                    userID,
+		"TIMESTAMP":                   timestamp,
+		"PASSWORD_CLAIM_SECRET_BLOCK": challenge["SECRET_BLOCK"],
+		"PASSWORD_CLAIM_SIGNATURE":    base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+	}, nil
+}
 
-			if len(folderName) == 0 {
-				_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/status", &fileStatus)
-				_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/filename", &s1)
-				_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/folder", &s2)
-				_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/decryptHint", &decryptHint)
-				if regexp.MustCompile(`^[0-9]+$`).Match([]byte(arg[j])) {
-					// ID
-					_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/id", &fileID)
-					if fileID == arg[j] && (status == fileStatus || status == "") {
-						if fullPath {
-							// for "remove" command
-							nameList = append(nameList, s2+s1)
-						} else {
-							nameList = append(nameList, s1)
-						}
-						id, _ = strconv.Atoi(fileID)
-						idList = append(idList, id)
-						hintList = append(hintList, decryptHint)
-					}
-				} else {
-					// name
-					if (fileName == "" || comparePath(fileName, s1)) && (status == fileStatus || status == "") {
-						if fullPath {
-							// for "remove" command
-							nameList = append(nameList, s2+s1)
-						} else {
-							nameList = append(nameList, s1)
-						}
-						_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/id", &fileID)
-						id, _ = strconv.Atoi(fileID)
-						idList = append(idList, id)
-						hintList = append(hintList, decryptHint)
-					}
-				}
-			} else {
-				_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/status", &fileStatus)
-				_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/folder", &s1)
-				_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/filename", &s2)
-				_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/decryptHint", &decryptHint)
-				if (status == fileStatus || status == "") && (comparePath(s1, folderName) || comparePath(s1+s2, fileName)) {
-					if fullPath {
-						// for "remove" command
-						nameList = append(nameList, s1+s2)
-					} else {
-						nameList = append(nameList, s2)
-					}
-					_ = scan.ScanTree(v, "/response/databases["+strconv.Itoa(i)+"]/id", &fileID)
-					id, _ = strconv.Atoi(fileID)
-					idList = append(idList, id)
-					hintList = append(hintList, decryptHint)
-				}
-			}
+// loginWithClarisID signs in to Claris FileMaker Cloud. The refresh token in
+// FMC_REFRESH_TOKEN is used when no username and password are specified.
+func loginWithClarisID(out io.Writer, baseURI string, user string, pass string, p params) (string, int, error) {
+	token := ""
+	idToken := ""
+	refreshToken := ""
+	exitStatus := 0
+	var err error
+
+	if len(user) == 0 && len(pass) == 0 && !p.printRefreshToken && len(os.Getenv("FMC_REFRESH_TOKEN")) > 0 {
+		idToken, _, exitStatus, err = getClarisIDTokens("REFRESH_TOKEN_AUTH", map[string]string{"REFRESH_TOKEN": os.Getenv("FMC_REFRESH_TOKEN")})
+		if exitStatus == 10502 {
+			return token, exitStatus, err
+		} else if exitStatus != 0 {
+			fmt.Fprintln(out, "fmcsadmin: The refresh token is invalid or has expired.")
 		}
 	}
 
-	if fullPath {
-		// for "remove" command
-		for i := 0; i < len(nameList); i++ {
-			if strings.Index(nameList[i], "filelinux:/") == 0 {
-				nameList[i] = strings.Replace(nameList[i], "filelinux:/", "/", 1)
-			} else if strings.Index(nameList[i], "filewin:/") == 0 {
-				nameList[i] = strings.Replace(strings.Replace(nameList[i], "filewin:/", "", 1), "/", "¥", -1)
-			} else if strings.Index(nameList[i], "filemac:/") == 0 {
-				nameList[i] = strings.Replace(nameList[i], "filemac:/", "/Volumes/", 1)
+	if idToken == "" {
+		username, password, _ := getUsernameAndPassword(user, pass, 2)
+		idToken, refreshToken, exitStatus, err = getClarisIDTokensWithPassword(username, password)
+		if exitStatus == 9 {
+			if p.retry > 0 {
+				fmt.Fprintln(out, "fmcsadmin: Permission denied, please try again.")
+				retryParams := p
+				retryParams.retry = p.retry - 1
+				return loginWithClarisID(out, baseURI, user, "", retryParams)
 			}
+			fmt.Fprintln(out, "fmcsadmin: Permission denied.")
+			return token, exitStatus, err
+		} else if exitStatus != 0 {
+			return token, exitStatus, err
 		}
 	}
 
-	return idList, nameList, hintList
-}
-
-func getClients(url string, token string, arg []string) []int {
-	var fileName string
-	var folderName string
-	var idList []int
-	var id int
-
-	body, _, err := callURL("GET", url, token, nil)
+	u, _ := url.Parse(baseURI)
+	u.Path = path.Join(getAPIBasePath(), "user", "auth")
+	body, _, err := callURL("POST", u.String(), "FMID "+idToken, nil)
 	if err != nil {
-		fmt.Println(err.Error())
-		return idList
+		return token, 10502, err
 	}
 
-	var v interface{}
-	err = json.Unmarshal(body, &v)
+	output := output{}
+	err = json.Unmarshal(body, &output)
 	if err != nil {
-		fmt.Println(err.Error())
+		return token, exitStatus, err
 	}
-	var clients []string
-	var guestFiles []string
-	var files []string
-	var guestFileID string
-	var guestFileName string
-	var clientID string
-	var fileID string
-	var directory string
 
-	for i := 0; i < len(arg)+1; i++ {
-		if i == len(arg) && i > 0 {
-			break
-		}
+	if len(output.Messages) == 0 || output.Messages[0].Code != "0" || output.Response.Token == "" {
+		fmt.Fprintln(out, "fmcsadmin: Permission denied.")
+		return token, 9, err
+	}
+	token = output.Response.Token
 
-		fileName = ""
-		folderName = ""
-		if len(arg) > 0 {
-			fileName = arg[i]
-			if strings.Contains(fileName, string(os.PathSeparator)) {
-				folderName = fileName
-			}
-		}
+	if p.printRefreshToken {
+		fmt.Fprintln(out, refreshToken)
+	}
 
-		_ = scan.ScanTree(v, "/response/clients", &clients)
-		for j := 0; j < len(clients); j++ {
-			_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(j)+"]/guestFiles", &guestFiles)
-			for k := 0; k < len(guestFiles); k++ {
-				_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(j)+"]/guestFiles["+strconv.Itoa(k)+"]/filename", &guestFileID)
-				_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(j)+"]/guestFiles["+strconv.Itoa(k)+"]/filename", &guestFileName)
-				if len(folderName) == 0 {
-					if fileName == "" || comparePath(fileName, guestFileName) {
-						_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(k)+"]/id", &clientID)
-						id, _ = strconv.Atoi(clientID)
-						idList = append(idList, id)
-					}
-				} else {
-					_ = scan.ScanTree(v, "/files/files", &files)
-					for l := 0; k < len(files); l++ {
-						_ = scan.ScanTree(v, "/files/files["+strconv.Itoa(k)+"]/id", &fileID)
-						_ = scan.ScanTree(v, "/files/files["+strconv.Itoa(k)+"]/folder", &directory)
-						if fileID == guestFileID {
-							if comparePath(fileName, directory+guestFileName) {
-								_ = scan.ScanTree(v, "/response/clients["+strconv.Itoa(k)+"]/id", &clientID)
-								id, _ = strconv.Atoi(clientID)
-								idList = append(idList, id)
-							}
-						}
-					}
+	return token, exitStatus, err
+}
+
+type shellSession struct {
+	baseURI  string
+	token    string
+	username string
+	password string
+	p        params
+	// out is the output stream for the messages of logging in again
+	out io.Writer
+	// renewed is true while the command is run again after renewing the session
+	renewed bool
+}
+
+// login logs in to the server, or reuses the session of the shell running the command.
+func (c *cli) login(baseURI string, user string, pass string, p params) (string, int, error) {
+	if c.session != nil && c.session.baseURI == baseURI && c.session.token != "" {
+		return c.session.token, 0, nil
+	}
+
+	return login(c.outStream, baseURI, user, pass, p)
+}
+
+// logout logs out from the server unless the session of the shell is used,
+// which is closed when exiting the shell.
+func (c *cli) logout(baseURI string, token string) {
+	if c.session != nil && c.session.token == token {
+		return
+	}
+
+	logout(baseURI, token)
+}
+
+func runShell(c *cli, baseURI string, fqdn string, username string, password string, p params) int {
+	exitStatus := 0
+
+	// resolve the credentials once so that the session can be renewed without prompting
+	if p.identityFile == "" {
+		username, password, _, exitStatus = getStoredCredentials(c.outStream, baseURI, username, password, p.credentialHelper)
+		if exitStatus != 0 {
+			return exitStatus
+		}
+		product := 1
+		if isCloudURI(baseURI) {
+			product = 2
+		}
+		username, password, _ = getUsernameAndPassword(username, password, product)
+	}
+
+	token, exitStatus, err := login(c.outStream, baseURI, username, password, params{identityFile: p.identityFile, credentialHelper: p.credentialHelper})
+	if token == "" || exitStatus != 0 || err != nil {
+		if detectHostUnreachable(exitStatus) {
+			exitStatus = 10502
+		}
+		return exitStatus
+	}
+
+	s := &shellSession{baseURI: baseURI, token: token, username: username, password: password, p: params{identityFile: p.identityFile, credentialHelper: p.credentialHelper}, out: c.outStream}
+	defer func() {
+		logout(s.baseURI, s.token)
+	}()
+	sc := &cli{inStream: c.inStream, reader: c.input(), outStream: c.outStream, errStream: c.errStream, session: s}
+
+	var readLine func() (string, error)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		t := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, c.outStream}, "fmcsadmin> ")
+		t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+			return s.complete(t, line, pos, key)
+		}
+		readLine = func() (string, error) {
+			// use the raw mode only while editing a line so that the output of commands is not affected
+			oldState, err := term.MakeRaw(fd)
+			if err != nil {
+				return "", err
+			}
+			defer term.Restore(fd, oldState)
+			return t.ReadLine()
+		}
+	} else {
+		readLine = func() (string, error) {
+			line, err := c.input().ReadString('\n')
+			if err != nil && len(line) == 0 {
+				return "", err
+			}
+			return strings.TrimRight(line, "\r\n"), nil
+		}
+	}
+
+	for {
+		line, err := readLine()
+		if err != nil {
+			// EOF (Ctrl-D)
+			fmt.Fprintln(c.outStream, "")
+			break
+		}
+
+		cmdArgs, err := splitCommandLine(line)
+		if err != nil {
+			fmt.Fprintln(c.outStream, "fmcsadmin: "+err.Error())
+			continue
+		}
+		if len(cmdArgs) == 0 {
+			continue
+		}
+
+		command := strings.ToLower(cmdArgs[0])
+		if command == "exit" || command == "quit" {
+			break
+		} else if command == "shell" {
+			fmt.Fprintln(c.outStream, "fmcsadmin: already running the shell")
+			continue
+		}
+
+		args := append([]string{"fmcsadmin"}, cmdArgs...)
+		if len(fqdn) > 0 {
+			args = append(args, "--fqdn", fqdn)
+		}
+		status := sc.Run(args)
+		if s.expired() {
+			// log in again and run the command again with the new session
+			if status = s.renew(); status == 0 {
+				s.renewed = true
+				status = sc.Run(args)
+				s.renewed = false
+			} else {
+				outputErrorMessage(status, c)
+			}
+		}
+		if status != 0 {
+			exitStatus = status
+		}
+	}
+
+	return exitStatus
+}
+
+// expired reports whether the last command was rejected because the session has expired
+func (s *shellSession) expired() bool {
+	return s != nil && !s.renewed && lastAPIError != nil && lastAPIError.statusCode == 401
+}
+
+// renew logs in again after the session has expired
+func (s *shellSession) renew() int {
+	s.token = ""
+	token, exitStatus, err := login(s.out, s.baseURI, s.username, s.password, s.p)
+	if token == "" || exitStatus != 0 || err != nil {
+		if detectHostUnreachable(exitStatus) {
+			exitStatus = 10502
+		}
+		return exitStatus
+	}
+	s.token = token
+
+	return 0
+}
+
+func (s *shellSession) complete(t *term.Terminal, line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	head := line[:pos]
+	words := strings.Fields(head)
+	if len(words) == 0 || strings.HasSuffix(head, " ") {
+		words = append(words, "")
+	}
+	prefix := words[len(words)-1]
+
+	matches := getCompletionCandidates(words[:len(words)-1], prefix, func(kind string) []string {
+		return getCompletionNames(s.baseURI, s.token, kind)
+	})
+	if len(words) == 1 {
+		for _, command := range []string{"exit", "quit"} {
+			if strings.HasPrefix(command, strings.ToLower(prefix)) {
+				matches = append(matches, command)
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+
+	completion := matches[0]
+	if len(matches) > 1 {
+		completion = commonPrefix(matches)
+		if len(completion) <= len(prefix) {
+			// show the candidates
+			fmt.Fprintln(t, strings.Join(matches, "  "))
+			return "", 0, false
+		}
+	} else {
+		completion += " "
+	}
+
+	newHead := head[:len(head)-len(prefix)] + completion
+	return newHead + line[pos:], len(newHead), true
+}
+
+func getCompletionCandidates(words []string, current string, names func(kind string) []string) []string {
+	var candidates []string
+
+	// skip options and their values
+	var positional []string
+	last := ""
+	for i := 0; i < len(words); i++ {
+		word := strings.ToLower(words[i])
+		if strings.HasPrefix(word, "-") {
+			if slices.Contains(valueOptions, word) {
+				if i == len(words)-1 {
+					// the current word is the value of the option
+					last = word
+				}
+				i++
+			}
+			continue
+		}
+		positional = append(positional, word)
+	}
+
+	command := ""
+	if len(positional) > 0 {
+		command = positional[0]
+	}
+
+	switch {
+	case last == "-c" || last == "--client":
+		candidates = names("clients")
+	case last == "--sort":
+		candidates = []string{"connectTime", "duration", "user"}
+	case last != "":
+		// the value of an option
+		return nil
+	case strings.HasPrefix(current, "-"):
+		for _, option := range allowedOptions {
+			if option == strings.ToLower(option) {
+				candidates = append(candidates, option)
+			}
+		}
+	case len(positional) == 0:
+		for name := range commandTree {
+			candidates = append(candidates, name)
+		}
+	case command == "help":
+		if len(positional) == 1 {
+			for name := range commandTree {
+				candidates = append(candidates, name)
+			}
+			candidates = append(candidates, commandTree["help"]...)
+		}
+	case command == "get" || command == "set":
+		if len(positional) == 1 {
+			candidates = commandTree[command]
+		} else if positional[1] == "backuptime" && len(positional) == 2 {
+			candidates = names("schedules")
+		} else {
+			for _, key := range configNames[positional[1]] {
+				if command == "set" {
+					key += "="
+				}
+				candidates = append(candidates, key)
+			}
+		}
+	case command == "close" || command == "open" || command == "pause" || command == "remove" || command == "resume" || command == "send":
+		candidates = names("databases")
+	case len(positional) == 1:
+		candidates = commandTree[command]
+	case len(positional) == 2 && positional[1] == "schedule":
+		candidates = names("schedules")
+	case len(positional) == 2 && positional[1] == "client":
+		candidates = names("clients")
+	case len(positional) == 2 && command == "status" && positional[1] == "file":
+		candidates = names("databases")
+	}
+
+	var result []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(current)) {
+			result = append(result, candidate)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+func getCompletionNames(baseURI string, token string, kind string) []string {
+	var list []string
+
+	u, _ := url.Parse(baseURI)
+	switch kind {
+	case "databases":
+		u.Path = path.Join(getAPIBasePath(), "databases")
+		_, list, _ = getDatabases(u.String(), token, []string{""}, "", false)
+	case "clients":
+		u.Path = path.Join(getAPIBasePath(), "clients")
+		clients, _ := getClientInfo(io.Discard, u.String(), token)
+		for _, client := range clients {
+			list = append(list, client.id)
+		}
+	case "schedules":
+		u.Path = path.Join(getAPIBasePath(), "schedules")
+		body, _, err := callURL("GET", u.String(), token, nil)
+		if err != nil {
+			return list
+		}
+		var response schedulesResponse
+		if decodeResponse(body, &response) != nil {
+			return list
+		}
+		for _, schedule := range response.Response.Schedules {
+			list = append(list, string(schedule.ID))
+		}
+	}
+
+	return list
+}
+
+func outputCompletionCandidates(c *cli, args []string) int {
+	// fmcsadmin __complete COUNT WORD1 ... WORDn [CURRENT]
+	if len(args) == 0 {
+		return 0
+	}
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 0 || count > len(args)-1 {
+		return 0
+	}
+	words := args[1 : count+1]
+	current := ""
+	if len(args) > count+1 {
+		current = args[count+1]
+	}
+
+	fqdn := ""
+	for i := 0; i < len(words)-1; i++ {
+		if strings.ToLower(words[i]) == "--fqdn" {
+			fqdn = words[i+1]
+		}
+	}
+
+	names := func(kind string) []string {
+		if os.Getenv("FMCSADMIN_DYNAMIC_COMPLETION") == "" {
+			return nil
+		}
+
+		// never prompt while completing
+		stdout := os.Stdout
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			return nil
+		}
+		os.Stdout = devNull
+		defer func() {
+			os.Stdout = stdout
+			devNull.Close()
+		}()
+
+		baseURI := getBaseURI(fqdn)
+		username := os.Getenv("FMS_USERNAME")
+		password := os.Getenv("FMS_PASSWORD")
+		if len(password) == 0 && (len(getCredentialHelper("")) > 0 || len(os.Getenv("FMCSADMIN_MASTER_PASSPHRASE")) > 0) {
+			username, password, _, _ = getStoredCredentials(io.Discard, baseURI, username, "", "")
+		}
+		if len(username) == 0 || len(password) == 0 {
+			return nil
+		}
+
+		// c.outStream is the standard output of the completion, so errors are discarded
+		token, exitStatus, err := login(io.Discard, baseURI, username, password, params{})
+		if token == "" || exitStatus != 0 || err != nil {
+			return nil
+		}
+		defer logout(baseURI, token)
+
+		return getCompletionNames(baseURI, token, kind)
+	}
+
+	for _, candidate := range getCompletionCandidates(words, current, names) {
+		fmt.Fprintln(c.outStream, candidate)
+	}
+
+	return 0
+}
+
+func outputCompletionScript(c *cli, shell string) int {
+	switch strings.ToLower(shell) {
+	case "bash":
+		fmt.Fprint(c.outStream, bashCompletionScript)
+	case "zsh":
+		fmt.Fprint(c.outStream, zshCompletionScript)
+	case "fish":
+		fmt.Fprint(c.outStream, fishCompletionScript)
+	case "powershell":
+		fmt.Fprint(c.outStream, powershellCompletionScript)
+	default:
+		return outputInvalidCommandErrorMessage(c)
+	}
+
+	return 0
+}
+
+var bashCompletionScript = `# bash completion for fmcsadmin
+# Add the following line to ~/.bashrc:
+#     source <(fmcsadmin completion bash)
+
+_fmcsadmin() {
+    local IFS=$'\n'
+    local candidates
+    candidates=($(fmcsadmin __complete $((COMP_CWORD - 1)) "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    COMPREPLY=("${candidates[@]}")
+    if [[ ${#COMPREPLY[@]} -eq 1 && "${COMPREPLY[0]}" == *= ]]; then
+        compopt -o nospace
+    fi
+}
+
+complete -o default -F _fmcsadmin fmcsadmin
+`
+
+var zshCompletionScript = `#compdef fmcsadmin
+# zsh completion for fmcsadmin
+# Add the following line to ~/.zshrc (after compinit):
+#     source <(fmcsadmin completion zsh)
+
+_fmcsadmin() {
+    local -a candidates keys
+    local candidate
+    for candidate in "${(@f)$(fmcsadmin __complete $((CURRENT - 2)) "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        if [[ "$candidate" == *= ]]; then
+            keys+=("$candidate")
+        elif [[ -n "$candidate" ]]; then
+            candidates+=("$candidate")
+        fi
+    done
+    (( ${#keys} )) && compadd -S '' -- "${keys[@]}"
+    (( ${#candidates} )) && compadd -- "${candidates[@]}"
+}
+
+if [ "$funcstack[1]" = "_fmcsadmin" ]; then
+    _fmcsadmin "$@"
+else
+    compdef _fmcsadmin fmcsadmin
+fi
+`
+
+var fishCompletionScript = `# fish completion for fmcsadmin
+# Save the output to ~/.config/fish/completions/fmcsadmin.fish
+
+function __fmcsadmin_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l current (commandline -ct)
+    fmcsadmin __complete (count $tokens) $tokens "$current" 2>/dev/null
+end
+
+complete -c fmcsadmin -f -a '(__fmcsadmin_complete)'
+`
+
+var powershellCompletionScript = `# PowerShell completion for fmcsadmin
+# Add the following line to your PowerShell profile:
+#     fmcsadmin completion powershell | Out-String | Invoke-Expression
+
+Register-ArgumentCompleter -Native -CommandName fmcsadmin -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.Extent.Text })
+    if ($wordToComplete -ne '') {
+        $words = @($words | Select-Object -SkipLast 1)
+    }
+    & fmcsadmin __complete $words.Count @words $wordToComplete 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
+
+func commonPrefix(list []string) string {
+	prefix := list[0]
+	for _, s := range list[1:] {
+		for !strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
+
+func splitCommandLine(line string) ([]string, error) {
+	var args []string
+	var current []rune
+	var quote rune
+	inArg := false
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current = append(current, r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current = append(current, r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, string(current))
+				current = nil
+				inArg = false
+			}
+		default:
+			current = append(current, r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+	if inArg {
+		args = append(args, string(current))
+	}
+
+	return args, nil
+}
+
+func getJWTToken(filePath string) (string, int, error) {
+	// for public key infrastructure (PKI) authentication
+	var err error
+	var pkey *rsa.PrivateKey
+	passphrase := ""
+
+	keyData, keyFormat, exitStatus := detectPrivateKeyFormat(filePath, "")
+	if exitStatus != 0 && exitStatus != 212 {
+		return "", exitStatus, nil
+	}
+
+	if exitStatus == 212 {
+		fmt.Print("Enter passphrase: ")
+		bytePassphrase, _ := term.ReadPassword(int(syscall.Stdin))
+		passphrase = string(bytePassphrase)
+		fmt.Printf("\n")
+		keyData, _, exitStatus = detectPrivateKeyFormat(filePath, passphrase)
+	} else if keyFormat == "PRIVATE KEY" || keyFormat == "EC PRIVATE KEY" || keyFormat == "EC PARAMETERS" {
+		exitStatus = 21
+		return "", exitStatus, nil
+	}
+
+	// Name of public key on FileMaker Server Admin Console
+	keyName := strings.Replace(filepath.Base(filePath), filepath.Ext(filePath), "", 1)
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": strings.Replace(keyName, "_", " ", -1),
+		"aud": "fmsadminapi",
+		"exp": time.Now().Add(time.Minute * 15).Unix(),
+	})
+
+	if passphrase == "" {
+		pkey, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(keyData))
+		if err != nil {
+			exitStatus = 20408
+			return "", exitStatus, err
+		}
+	} else {
+		pkey, err = jwt.ParseRSAPrivateKeyFromPEMWithPassword([]byte(keyData), passphrase)
+		if err != nil {
+			exitStatus = 20408
+			return "", exitStatus, err
+		}
+	}
+	tokenString, _ := jwtToken.SignedString(pkey)
+
+	return tokenString, exitStatus, err
+}
+
+func detectPrivateKeyFormat(filePath string, keyFilePass string) ([]byte, string, int) {
+	keyType := ""
+	exitStatus := 0
+
+	_, err := os.Stat(filePath)
+	if err != nil {
+		exitStatus = 20405
+	}
+
+	keyData, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsPermission(err) {
+			exitStatus = 20402
+		} else {
+			exitStatus = 20405
+		}
+	} else {
+		block, _ := pem.Decode(keyData)
+		if block == nil {
+			exitStatus = 20408
+		} else {
+			buf := block.Bytes
+			if x509.IsEncryptedPEMBlock(block) {
+				buf, err = x509.DecryptPEMBlock(block, []byte(keyFilePass))
+				if err != nil {
+					if err == x509.IncorrectPasswordError {
+						exitStatus = 212
+					}
+				}
+			}
+
+			if exitStatus == 0 {
+				switch block.Type {
+				case "RSA PRIVATE KEY":
+					_, err = x509.ParsePKCS1PrivateKey(buf)
+					if err != nil {
+						exitStatus = 20408
+					}
+				case "PRIVATE KEY":
+					_, err := x509.ParsePKCS8PrivateKey(buf)
+					if err != nil {
+						exitStatus = 20408
+					}
+				case "EC PRIVATE KEY":
+					_, err := x509.ParseECPrivateKey(buf)
+					if err != nil {
+						exitStatus = 20408
+					}
+				default:
+					exitStatus = 20408
+				}
+				keyType = block.Type
+			}
+		}
+	}
+
+	return keyData, keyType, exitStatus
+}
+
+func logout(baseURI string, token string) {
+	u, _ := url.Parse(baseURI)
+	u.Path = path.Join(getAPIBasePath(), "user", "auth", token)
+	sendRequest("DELETE", u.String(), token, params{})
+}
+
+// decodeResponse decodes the body of a response of Claris FileMaker Admin API.
+// A field of an unexpected type is left as the zero value and reported in the
+// error, while the other fields are decoded.
+func decodeResponse(body []byte, v interface{}) error {
+	err := json.Unmarshal(body, v)
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		return fmt.Errorf("unexpected %s value of %s in the response (expected %s)", typeError.Value, typeError.Field, typeError.Type)
+	}
+
+	return err
+}
+
+func getResultCode(messages []apiMessage) int {
+	if len(messages) == 0 {
+		return -1
+	}
+	code, _ := strconv.Atoi(messages[0].Code)
+
+	return code
+}
+
+// getListResultCode returns the result code of a response listing clients or
+// databases, so that a failed request is not taken as an empty list.
+func getListResultCode(messages []apiMessage, statusCode int) int {
+	result := getResultCode(messages)
+	switch {
+	case result == 1701:
+		// when fmserverd is stopping
+		return 10502
+	case result > 0:
+		return result
+	case result < 0 && statusCode >= 400:
+		return 10001
+	case result < 0:
+		// In case of detecting a server-side error
+		return 3
+	}
+
+	return 0
+}
+
+type clientInfo struct {
+	id              string
+	userName        string
+	computerName    string
+	extPriv         string
+	ipAddress       string
+	macAddress      string
+	connectTime     string
+	rawConnectTime  string
+	connectDuration string
+	appVersion      string
+	appLanguage     string
+	guestFiles      []guestFileInfo
+}
+
+type guestFileInfo struct {
+	fileName    string
+	accountName string
+	privsetName string
+}
+
+type clientListOptions struct {
+	fileName   string
+	userName   string
+	appVersion string
+	ipAddress  string
+	sortKey    string
+	outputOptions
+}
+
+func listClients(c *cli, urlString string, token string, id int, opts clientListOptions) int {
+	usingCloud := isCloudURI(urlString)
+
+	clients, exitStatus := getClientInfo(c.outStream, urlString, token)
+	if exitStatus != 0 {
+		return exitStatus
+	}
+
+	mode := "NORMAL"
+	if id > -1 {
+		mode = "DETAIL"
+	}
+
+	clients = filterClients(clients, opts)
+	sortClients(clients, opts.sortKey)
+
+	var data [][]string
+	count := 0
+	if mode == "NORMAL" {
+		if len(clients) > 0 {
+			for _, client := range clients {
+				data = append(data, []string{client.id, client.userName, client.computerName, client.extPriv})
+			}
+
+			table := tablewriter.NewWriter(c.outStream)
+			table.SetHeader([]string{"Client ID", "User Name", "Computer Name", "Ext Privilege"})
+			table.SetAutoWrapText(false)
+			table.SetAutoFormatHeaders(false)
+			for _, v := range data {
+				table.Append(v)
+			}
+			if opts.human {
+				table.SetFooter([]string{"Total", strconv.Itoa(len(clients)), "", ""})
+			}
+			table.Render()
+		}
+	} else {
+		for _, client := range clients {
+			sID, _ := strconv.Atoi(client.id)
+			if id != sID && id != 0 {
+				continue
+			}
+
+			files := client.guestFiles
+			if len(files) == 0 {
+				files = []guestFileInfo{{}}
+			}
+			connectTime := client.connectTime
+			if opts.location != nil {
+				connectTime = opts.formatDateTime(client.rawConnectTime, "2006/01/02 15:04:05", usingCloud)
+			}
+			connectDuration := client.connectDuration
+			if opts.human && len(connectDuration) > 0 {
+				connectDuration = formatDuration(parseConnectDuration(connectDuration))
+			}
+			count++
+			for i, file := range files {
+				fileName := file.fileName
+				if regexp.MustCompile(`(.*)\.fmp12`).Match([]byte(fileName)) {
+					rep := regexp.MustCompile(`(.*)\.fmp12`)
+					fileName = rep.ReplaceAllString(fileName, "$1")
+				}
+				if i == 0 {
+					data = append(data, []string{client.id, client.userName, client.computerName, client.extPriv, client.ipAddress, client.macAddress, connectTime, connectDuration, client.appVersion, client.appLanguage, fileName, file.accountName, file.privsetName})
+				} else {
+					// list the other guest files of the same client
+					data = append(data, []string{"", "", "", "", "", "", "", "", "", "", fileName, file.accountName, file.privsetName})
+				}
+			}
+		}
+
+		if len(data) > 0 {
+			table := tablewriter.NewWriter(c.outStream)
+			table.SetHeader([]string{"Client ID", "User Name", "Computer Name", "Ext Privilege", "IP Address", "MAC Address", "Connect Time", "Duration", "App Version", "App Language", "File Name", "Account Name", "Privilege Set"})
+			table.SetAutoWrapText(false)
+			table.SetAutoFormatHeaders(false)
+			for _, v := range data {
+				table.Append(v)
+			}
+			if opts.human {
+				table.SetFooter([]string{"Total", strconv.Itoa(count), "", "", "", "", "", "", "", "", "", "", ""})
+			}
+			table.Render()
+		}
+	}
+
+	return 0
+}
+
+func getClientInfo(out io.Writer, urlString string, token string) ([]clientInfo, int) {
+	usingCloud := isCloudURI(urlString)
+
+	body, statusCode, err := callURL("GET", urlString, token, nil)
+	if err != nil {
+		fmt.Fprintln(out, err.Error())
+		return nil, -1
+	}
+
+	var response clientsResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		fmt.Fprintln(out, err.Error())
+	}
+
+	result := getListResultCode(response.Messages, statusCode)
+	if result != 0 {
+		return nil, result
+	}
+
+	return getClientInfoList(response.Response.Clients, usingCloud), 0
+}
+
+func getClientInfoList(records []clientRecord, usingCloud bool) []clientInfo {
+	var clients []clientInfo
+
+	for _, record := range records {
+		if record.Status != "NORMAL" {
+			continue
+		}
+
+		client := clientInfo{
+			id:              string(record.ID),
+			userName:        record.UserName,
+			computerName:    record.ComputerName,
+			extPriv:         record.ExtPriv,
+			ipAddress:       record.IPAddress,
+			macAddress:      record.MACAddress,
+			rawConnectTime:  record.ConnectTime,
+			connectDuration: record.ConnectDuration,
+			appVersion:      record.AppVersion,
+			appLanguage:     record.AppLanguage,
+		}
+		client.connectTime = getDateTimeStringOfCurrentTimeZone(record.ConnectTime, "2006/01/02 15:04:05", usingCloud)
+
+		for _, file := range record.GuestFiles {
+			client.guestFiles = append(client.guestFiles, guestFileInfo{
+				fileName:    file.FileName,
+				accountName: file.AccountName,
+				privsetName: file.PrivsetName,
+			})
+		}
+
+		clients = append(clients, client)
+	}
+
+	return clients
+}
+
+func validateClientListOptions(opts clientListOptions) string {
+	if len(opts.ipAddress) > 0 {
+		if strings.Contains(opts.ipAddress, "/") {
+			if _, _, err := net.ParseCIDR(opts.ipAddress); err != nil {
+				return "--ip"
+			}
+		} else if net.ParseIP(opts.ipAddress) == nil {
+			return "--ip"
+		}
+	}
+
+	switch strings.ToLower(opts.sortKey) {
+	case "", "connecttime", "duration", "user":
+	default:
+		return "--sort"
+	}
+
+	return ""
+}
+
+func filterClients(clients []clientInfo, opts clientListOptions) []clientInfo {
+	var result []clientInfo
+	for _, client := range clients {
+		if len(opts.userName) > 0 && !matchClientUserName(opts.userName, client.userName) {
+			continue
+		}
+		if len(opts.appVersion) > 0 && !strings.Contains(strings.ToLower(client.appVersion), strings.ToLower(opts.appVersion)) {
+			continue
+		}
+		if len(opts.ipAddress) > 0 && !matchClientIPAddress(opts.ipAddress, client.ipAddress) {
+			continue
+		}
+		if len(opts.fileName) > 0 {
+			found := false
+			for _, file := range client.guestFiles {
+				if comparePath(file.fileName, opts.fileName) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		result = append(result, client)
+	}
+
+	return result
+}
+
+func matchClientUserName(pattern string, userName string) bool {
+	pattern = strings.ToLower(pattern)
+	userName = strings.ToLower(userName)
+	if pattern == userName {
+		return true
+	}
+
+	// allow wildcards (ex.: "--user 'j*'")
+	matched, err := path.Match(pattern, userName)
+	if err != nil {
+		return false
+	}
+
+	return matched
+}
+
+func matchClientIPAddress(filter string, ipAddress string) bool {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return false
+	}
+
+	if strings.Contains(filter, "/") {
+		_, ipNet, err := net.ParseCIDR(filter)
+		if err != nil {
+			return false
+		}
+		return ipNet.Contains(ip)
+	}
+
+	return ip.Equal(net.ParseIP(filter))
+}
+
+func sortClients(clients []clientInfo, sortKey string) {
+	switch strings.ToLower(sortKey) {
+	case "connecttime":
+		// oldest connection first
+		sort.SliceStable(clients, func(i, j int) bool {
+			return clients[i].connectTime < clients[j].connectTime
+		})
+	case "duration":
+		// longest connection first
+		sort.SliceStable(clients, func(i, j int) bool {
+			return parseConnectDuration(clients[i].connectDuration) > parseConnectDuration(clients[j].connectDuration)
+		})
+	case "user":
+		sort.SliceStable(clients, func(i, j int) bool {
+			return strings.ToLower(clients[i].userName) < strings.ToLower(clients[j].userName)
+		})
+	}
+}
+
+func parseConnectDuration(duration string) time.Duration {
+	// "HH:MM:SS" (hours can exceed 24) or seconds
+	parts := strings.Split(strings.TrimSpace(duration), ":")
+	seconds := 0
+	for _, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+type clientCondition struct {
+	field    string
+	operator string
+	value    string
+}
+
+func parseClientConditions(expr string) ([]clientCondition, error) {
+	var conditions []clientCondition
+
+	if len(strings.TrimSpace(expr)) == 0 {
+		return nil, fmt.Errorf("no condition is specified")
+	}
+
+	reg := regexp.MustCompile(`^\s*([A-Za-z]+)\s*(!=|<=|>=|=|~|<|>)\s*(.*?)\s*$`)
+	for _, term := range regexp.MustCompile(`(?i)\s+and\s+`).Split(strings.TrimSpace(expr), -1) {
+		m := reg.FindStringSubmatch(term)
+		if m == nil {
+			return nil, fmt.Errorf("invalid condition: %s", term)
+		}
+
+		field := strings.ToLower(m[1])
+		switch field {
+		case "user", "username":
+			field = "user"
+		case "computer", "computername":
+			field = "computer"
+		case "ip", "ipaddress":
+			field = "ip"
+		case "app", "appversion":
+			field = "appversion"
+		case "extpriv", "file", "duration":
+		default:
+			return nil, fmt.Errorf("unknown field: %s", m[1])
+		}
+
+		value := m[3]
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if len(value) == 0 {
+			return nil, fmt.Errorf("value is not specified: %s", term)
+		}
+
+		operator := m[2]
+		switch operator {
+		case "<", ">", "<=", ">=":
+			if field != "duration" && field != "appversion" {
+				return nil, fmt.Errorf("operator %s is not supported for %s", operator, field)
+			}
+		}
+		if field == "duration" {
+			if _, ok := parseDurationCondition(value); !ok {
+				return nil, fmt.Errorf("invalid duration: %s", value)
+			}
+		}
+		if field == "ip" && operator != "~" && !strings.Contains(value, "/") && net.ParseIP(value) == nil {
+			return nil, fmt.Errorf("invalid IP address: %s", value)
+		}
+		if field == "ip" && strings.Contains(value, "/") {
+			if _, _, err := net.ParseCIDR(value); err != nil {
+				return nil, fmt.Errorf("invalid CIDR block: %s", value)
+			}
+		}
+
+		conditions = append(conditions, clientCondition{field: field, operator: operator, value: value})
+	}
+
+	return conditions, nil
+}
+
+func selectClients(clients []clientInfo, conditions []clientCondition) []clientInfo {
+	var result []clientInfo
+	for _, client := range clients {
+		matched := true
+		for _, condition := range conditions {
+			if !matchClientCondition(client, condition) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, client)
+		}
+	}
+
+	return result
+}
+
+func matchClientCondition(client clientInfo, condition clientCondition) bool {
+	operator := condition.operator
+	value := condition.value
+
+	switch condition.field {
+	case "duration":
+		d := parseConnectDuration(client.connectDuration)
+		limit, _ := parseDurationCondition(value)
+		return compareOrdered(int64(d), int64(limit), operator)
+	case "appversion":
+		switch operator {
+		case "<", ">", "<=", ">=":
+			version := regexp.MustCompile(`\d+(\.\d+)*`).FindString(client.appVersion)
+			if version == "" {
+				return false
+			}
+			return compareOrdered(int64(compareVersionStrings(version, value)), 0, operator)
+		}
+		return matchClientString(client.appVersion, value, operator)
+	case "file":
+		matched := false
+		for _, file := range client.guestFiles {
+			if operator == "~" {
+				if strings.Contains(strings.ToLower(file.fileName), strings.ToLower(value)) {
+					matched = true
+				}
+			} else if comparePath(file.fileName, value) {
+				matched = true
+			}
+		}
+		if operator == "!=" {
+			return !matched
+		}
+		return matched
+	case "ip":
+		if operator == "~" {
+			return strings.Contains(client.ipAddress, value)
+		}
+		matched := matchClientIPAddress(value, client.ipAddress)
+		if operator == "!=" {
+			return !matched
+		}
+		return matched
+	case "user":
+		return matchClientString(client.userName, value, operator)
+	case "computer":
+		return matchClientString(client.computerName, value, operator)
+	case "extpriv":
+		return matchClientString(client.extPriv, value, operator)
+	}
+
+	return false
+}
+
+func matchClientString(s string, value string, operator string) bool {
+	switch operator {
+	case "=":
+		return matchClientUserName(value, s)
+	case "!=":
+		return !matchClientUserName(value, s)
+	case "~":
+		return strings.Contains(strings.ToLower(s), strings.ToLower(value))
+	}
+
+	return false
+}
+
+func compareOrdered(a int64, b int64, operator string) bool {
+	switch operator {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case ">":
+		return a > b
+	case "<=":
+		return a <= b
+	case ">=":
+		return a >= b
+	}
+
+	return false
+}
+
+func compareVersionStrings(v1 string, v2 string) int {
+	p1 := strings.Split(v1, ".")
+	p2 := strings.Split(v2, ".")
+	for i := 0; i < len(p1) || i < len(p2); i++ {
+		n1, n2 := 0, 0
+		if i < len(p1) {
+			n1, _ = strconv.Atoi(p1[i])
+		}
+		if i < len(p2) {
+			n2, _ = strconv.Atoi(p2[i])
+		}
+		if n1 < n2 {
+			return -1
+		} else if n1 > n2 {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func parseDurationCondition(value string) (time.Duration, bool) {
+	// "8h", "30m", "1h30m" or "HH:MM:SS"
+	if d, err := time.ParseDuration(value); err == nil {
+		return d, true
+	}
+	if regexp.MustCompile(`^\d+(:\d+)*$`).MatchString(value) {
+		return parseConnectDuration(value), true
+	}
+
+	return 0, false
+}
+
+func disconnectClients(c *cli, u *url.URL, token string, clients []clientInfo, message string, graceTime int) int {
+	exitStatus := 0
+	for _, client := range clients {
+		u.Path = path.Join(getAPIBasePath(), "clients", client.id)
+		u.RawQuery = "messageText=" + url.QueryEscape(message) + "&graceTime=" + url.QueryEscape(strconv.Itoa(graceTime))
+		result, _, err := sendRequest("DELETE", u.String(), token, params{command: "disconnect"})
+		if err != nil || result != 0 {
+			fmt.Fprintln(c.outStream, "Failed to disconnect client: "+client.id)
+			exitStatus = result
+		}
+	}
+	u.RawQuery = ""
+
+	return exitStatus
+}
+
+func outputClientSummary(c *cli, clients []clientInfo) {
+	table := tablewriter.NewWriter(c.outStream)
+	table.SetHeader([]string{"Client ID", "User Name", "Computer Name", "IP Address", "App Version", "Duration", "File Name"})
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	for _, client := range clients {
+		var fileNames []string
+		for _, file := range client.guestFiles {
+			fileNames = append(fileNames, strings.TrimSuffix(file.fileName, ".fmp12"))
+		}
+		table.Append([]string{client.id, client.userName, client.computerName, client.ipAddress, client.appVersion, client.connectDuration, strings.Join(fileNames, ", ")})
+	}
+	table.Render()
+}
+
+// getDatabaseRecords retrieves the hosted databases
+func getDatabaseRecords(out io.Writer, url string, token string) ([]databaseRecord, int) {
+	body, statusCode, err := callURL("GET", url, token, nil)
+	if err != nil {
+		fmt.Fprintln(out, err.Error())
+		return nil, -1
+	}
+
+	var response databasesResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		fmt.Fprintln(out, err.Error())
+	}
+
+	result := getListResultCode(response.Messages, statusCode)
+	if result != 0 {
+		return nil, result
+	}
+
+	return response.Response.Databases, 0
+}
+
+func listFiles(c *cli, url string, token string, idList []int, out outputOptions) int {
+	databases, exitStatus := getDatabaseRecords(c.outStream, url, token)
+	if exitStatus != 0 {
+		return exitStatus
+	}
+
+	mode := "NORMAL"
+	if (len(idList) == 1 && idList[0] > -1) || len(idList) > 1 {
+		mode = "DETAIL"
+	}
+
+	var data [][]string
+	var totalClients int
+	var totalSize int64
+
+	if mode == "NORMAL" {
+		for _, database := range databases {
+			if database.Status == "NORMAL" {
+				fmt.Fprint(c.outStream, database.Folder)
+				fmt.Fprintln(c.outStream, database.FileName)
+			}
+		}
+	} else {
+		for _, database := range databases {
+			for j := 0; j < len(idList); j++ {
+				if string(database.ID) == strconv.Itoa(idList[j]) || idList[j] == 0 {
+					extPriv := "-"
+					if database.Status != "CLOSED" {
+						extPriv = strings.Join(database.EnabledExtPrivileges, " ")
+					}
+
+					isEncrypted := "No"
+					if database.IsEncrypted {
+						isEncrypted = "Yes"
+					}
+
+					size := strconv.FormatInt(database.Size, 10)
+					if out.human {
+						size = formatSize(database.Size)
+					}
+					totalClients += database.Clients
+					totalSize += database.Size
+
+					status := database.Status
+					if status != "" {
+						status = status[:1] + strings.ToLower(status[1:])
+					}
+
+					data = append(data, []string{string(database.ID), database.FileName, strconv.Itoa(database.Clients), size, status, extPriv, isEncrypted})
+				}
+			}
+		}
+
+		table := tablewriter.NewWriter(c.outStream)
+		table.SetHeader([]string{"ID", "File", "Clients", "Size", "Status", "Enabled Extended Privileges", "Encrypted"})
+		table.SetAutoWrapText(false)
+		table.SetAutoFormatHeaders(false)
+		for _, v := range data {
+			table.Append(v)
+		}
+		if out.human {
+			table.SetFooter([]string{"", "Total", strconv.Itoa(totalClients), formatSize(totalSize), "", "", ""})
+		}
+		table.Render()
+	}
+
+	return 0
+}
+
+// serverVersionCache keeps the versions of the servers retrieved while running
+// a command so that /server/metadata is fetched once per command.
+var serverVersionCache map[string]string
+
+func getServerVersionString(urlString string, token string) (string, error) {
+	if versionString, ok := serverVersionCache[urlString+" "+token]; ok {
+		return versionString, nil
+	}
+
+	body, _, err := callURL("GET", urlString, token, nil)
+	if err != nil {
+		return "0.0.0", err
+	}
+
+	var response metadataResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		return "0.0.0", err
+	}
+	if response.Response.ServerVersion == "" {
+		return "0.0.0", errors.New("server version not found")
+	}
+	if serverVersionCache != nil {
+		serverVersionCache[urlString+" "+token] = response.Response.ServerVersion
+	}
+
+	return response.Response.ServerVersion, nil
+}
+
+// serverVersion is a version of Claris FileMaker Server such as 21.1.1.40.
+type serverVersion struct {
+	major int
+	minor int
+	patch int
+	build int
+}
+
+func parseServerVersion(versionString string) (serverVersion, error) {
+	var numbers [4]int
+	parts := strings.Split(strings.TrimSpace(versionString), ".")
+	if len(parts) < 2 || len(parts) > 4 {
+		return serverVersion{}, fmt.Errorf("invalid version: %q", versionString)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return serverVersion{}, fmt.Errorf("invalid version: %q", versionString)
+		}
+		numbers[i] = n
+	}
+
+	return serverVersion{major: numbers[0], minor: numbers[1], patch: numbers[2], build: numbers[3]}, nil
+}
+
+// compare returns -1, 0 or +1. The build number is compared only when both versions have it.
+func (v serverVersion) compare(w serverVersion) int {
+	a := []int{v.major, v.minor, v.patch, v.build}
+	b := []int{w.major, w.minor, w.patch, w.build}
+	n := len(a)
+	if v.build == 0 || w.build == 0 {
+		n = 3
+	}
+	for i := 0; i < n; i++ {
+		if a[i] < b[i] {
+			return -1
+		} else if a[i] > b[i] {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func (v serverVersion) atLeast(versionString string) bool {
+	w, err := parseServerVersion(versionString)
+	if err != nil {
+		return false
+	}
+
+	return v.compare(w) >= 0
+}
+
+func (v serverVersion) String() string {
+	s := strconv.Itoa(v.major) + "." + strconv.Itoa(v.minor) + "." + strconv.Itoa(v.patch)
+	if v.build > 0 {
+		s = s + "." + strconv.Itoa(v.build)
+	}
+
+	return s
+}
+
+type capability struct {
+	name string
+	// minVersion is the first version of Claris FileMaker Server supporting the capability
+	minVersion string
+	// maxVersion is the last version of Claris FileMaker Server supporting the capability
+	maxVersion string
+	// os limits the required versions to servers running on the operating system (GOOS)
+	os          string
+	cloud       bool
+	description string
+}
+
+// capabilities lists the commands and settings that depend on the version of the server.
+var capabilities = []capability{
+	{name: "cancel backup", minVersion: "19.5.1", description: "Cancel the currently running backup"},
+	{name: "certificate", minVersion: "19.2.1", description: "Manage SSL certificates"},
+	{name: "disable plugin", minVersion: "19.2.1", description: "Disable plug-ins"},
+	{name: "enable plugin", minVersion: "19.2.1", description: "Enable plug-ins"},
+	{name: "list plugins", minVersion: "19.2.1", description: "List plug-ins"},
+	{name: "remove", minVersion: "19.3.1", cloud: true, description: "Remove databases"},
+	{name: "connectorconfig enableodata", minVersion: "19.1.2", description: "OData"},
+	{name: "cwpconfig", minVersion: "19.6", os: "linux", cloud: true, description: "Custom Web Publishing configuration"},
+	{name: "serverprefs startuprestorationenabled", maxVersion: "19.1.1", description: "Startup restoration"},
+	{name: "serverprefs authenticatedstream", minVersion: "19.3.2", cloud: true, description: "Use FileMaker Data API authenticated stream"},
+	{name: "serverprefs parallelbackupenabled", minVersion: "19.5.1", description: "Parallel backup"},
+	{name: "serverprefs persistcacheenabled", minVersion: "20.1", description: "Persistent cache"},
+	{name: "serverprefs syncpersistcache", minVersion: "20.1", description: "Synchronize persistent cache"},
+	{name: "set serverprefs persistcacheenabled", minVersion: "21.0.1", description: "Change persistent cache"},
+	{name: "set serverprefs syncpersistcache", minVersion: "21.0.1", description: "Change synchronization of persistent cache"},
+	{name: "serverprefs databaseserverautorestart", minVersion: "21.0.1", description: "Restart Database Server automatically"},
+	{name: "serverprefs blocknewusersenabled", minVersion: "21.0.1", description: "Block new users"},
+	{name: "serverprefs enablehttpprotocolnetwork", minVersion: "21.1.1", description: "HTTPS tunneling for FileMaker clients"},
+	{name: "serverprefs onlyopenlastopeneddatabases", minVersion: "21.1.1", description: "Only open last opened databases"},
+}
+
+// getSetCapabilityName returns the name of the capability for changing the
+// setting. Some settings can be changed only with later versions than the
+// versions listing them.
+func getSetCapabilityName(name string) string {
+	if _, ok := getCapability("set " + name); ok {
+		return "set " + name
+	}
+
+	return name
+}
+
+func getCapability(name string) (capability, bool) {
+	for _, capability := range capabilities {
+		if capability.name == name {
+			return capability, true
+		}
+	}
+
+	return capability{}, false
+}
+
+// getRequiredVersionString returns the versions supporting the capability (e.g. "19.2.1 or later").
+func (c capability) getRequiredVersionString() string {
+	versions := "any version"
+	if c.minVersion != "" && c.maxVersion != "" {
+		versions = c.minVersion + " to " + c.maxVersion
+	} else if c.maxVersion != "" {
+		versions = c.maxVersion + " or earlier"
+	} else if c.minVersion != "" {
+		versions = c.minVersion + " or later"
+	}
+	if c.os != "" {
+		versions = versions + " on " + getOSName(c.os)
+	}
+
+	return versions
+}
+
+// isSupported reports whether the server of the version supports the command or setting.
+func isSupported(name string, versionString string, usingCloud bool) bool {
+	capability, ok := getCapability(name)
+	if !ok {
+		return true
+	}
+	if usingCloud {
+		return capability.cloud
+	}
+
+	version, err := parseServerVersion(versionString)
+	if err != nil {
+		return false
+	}
+	if capability.minVersion != "" && !version.atLeast(capability.minVersion) {
+		return false
+	}
+	if capability.maxVersion != "" {
+		maxVersion, _ := parseServerVersion(capability.maxVersion)
+		if version.compare(maxVersion) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isSupportedOn reports whether the server running on the operating system
+// (GOOS, or "" when it is unknown) supports the command or setting. The
+// required versions of a capability for another operating system don't apply.
+func isSupportedOn(name string, versionString string, usingCloud bool, goos string) bool {
+	if capability, ok := getCapability(name); ok && capability.os != "" && capability.os != goos {
+		return true
+	}
+
+	return isSupported(name, versionString, usingCloud)
+}
+
+// getLocalServerOS returns the operating system (GOOS) of the server when
+// fmcsadmin is running on the server, or "" otherwise.
+func getLocalServerOS(baseURI string) string {
+	u, err := url.Parse(baseURI)
+	if err != nil || u.Hostname() != "127.0.0.1" {
+		return ""
+	}
+
+	return runtime.GOOS
+}
+
+func outputCapabilityErrorMessage(c *cli, name string, versionString string, usingCloud bool) {
+	capability, _ := getCapability(name)
+	if usingCloud {
+		fmt.Fprintln(c.outStream, "fmcsadmin: "+strings.ToUpper(name)+" is not supported by Claris FileMaker Cloud")
+	} else {
+		fmt.Fprintln(c.outStream, "fmcsadmin: "+strings.ToUpper(name)+" requires FileMaker Server "+capability.getRequiredVersionString()+" (connected: "+versionString+")")
+	}
+}
+
+// requireCapability retrieves the version of the server and prints an error message
+// when the server doesn't support the command or setting.
+func requireCapability(c *cli, baseURI string, token string, name string, usingCloud bool) bool {
+	versionString := ""
+	if !usingCloud {
+		u, _ := url.Parse(baseURI)
+		u.Path = path.Join(getAPIBasePath(), "server", "metadata")
+		versionString, _ = getServerVersionString(u.String(), token)
+	}
+
+	if !isSupportedOn(name, versionString, usingCloud, getLocalServerOS(baseURI)) {
+		outputCapabilityErrorMessage(c, name, versionString, usingCloud)
+		return false
+	}
+
+	return true
+}
+
+func showServerInfo(c *cli, baseURI string, token string, usingCloud bool) int {
+	u, _ := url.Parse(baseURI)
+	u.Path = path.Join(getAPIBasePath(), "server", "metadata")
+	versionString, err := getServerVersionString(u.String(), token)
+	if err != nil {
+		return 10502
+	}
+
+	u.Path = path.Join(getAPIBasePath(), "server", "status")
+	exitStatus, running, err := sendRequest("GET", u.String(), token, params{})
+	if exitStatus != 0 || err != nil {
+		return exitStatus
+	}
+
+	u.Path = path.Join(getAPIBasePath(), "databases")
+	databases, exitStatus := getDatabaseRecords(c.outStream, u.String(), token)
+	if exitStatus != 0 {
+		return exitStatus
+	}
+	opened := 0
+	for _, database := range databases {
+		if database.Status == "NORMAL" {
+			opened++
+		}
+	}
+
+	u.Path = path.Join(getAPIBasePath(), "clients")
+	clients, exitStatus := getClientInfo(c.outStream, u.String(), token)
+	if exitStatus != 0 {
+		return exitStatus
+	}
+
+	goos := getServerOS(databases)
+	if goos == "" && !usingCloud {
+		goos = getLocalServerOS(baseURI)
+	}
+	osName := "Unknown"
+	if goos != "" {
+		osName = getOSName(goos)
+	}
+
+	fmt.Fprintln(c.outStream, "Server Version:    "+versionString)
+	fmt.Fprintln(c.outStream, "Operating System:  "+osName)
+	fmt.Fprintln(c.outStream, "Database Server:   "+running)
+	fmt.Fprintln(c.outStream, "Hosted Databases:  "+strconv.Itoa(len(databases))+" ("+strconv.Itoa(opened)+" open)")
+	fmt.Fprintln(c.outStream, "Clients:           "+strconv.Itoa(len(clients)))
+	fmt.Fprintln(c.outStream, "")
+
+	table := tablewriter.NewWriter(c.outStream)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Command / Setting", "Description", "Requires", "Supported"})
+	for _, capability := range capabilities {
+		requires := capability.getRequiredVersionString()
+		if usingCloud {
+			requires = "-"
+		}
+		supported := "No"
+		if isSupportedOn(capability.name, versionString, usingCloud, goos) {
+			supported = "Yes"
+		}
+		table.Append([]string{strings.ToUpper(capability.name), capability.description, requires, supported})
+	}
+	table.Render()
+
+	return 0
+}
+
+// getServerOS returns the operating system (GOOS) of the server, which the
+// Admin API doesn't report, from the folder of a hosted database such as
+// "filelinux:/opt/FileMaker/FileMaker Server/Data/Databases/".
+func getServerOS(databases []databaseRecord) string {
+	for _, database := range databases {
+		switch {
+		case strings.HasPrefix(database.Folder, "filelinux:"):
+			return "linux"
+		case strings.HasPrefix(database.Folder, "filemac:"):
+			return "darwin"
+		case strings.HasPrefix(database.Folder, "filewin:"):
+			return "windows"
+		}
+	}
+
+	return ""
+}
+
+func getOSName(goos string) string {
+	switch goos {
+	case "darwin":
+		return "macOS"
+	case "linux":
+		return "Linux"
+	case "windows":
+		return "Windows"
+	}
+
+	return goos
+}
+
+// clientTypes are the types of clients in the order of "fmcsadmin status
+// server". The type of a client is detected from the prefix of appVersion.
+var clientTypes = []struct {
+	name     string
+	prefixes []string
+}{
+	{"FileMaker Pro", []string{"pro"}},
+	{"FileMaker Go", []string{"go"}},
+	{"WebDirect", []string{"webdirect", "webd"}},
+	{"Data API", []string{"fmdapi", "data api", "dapi"}},
+	{"OData", []string{"odata"}},
+	{"ODBC/JDBC", []string{"xdbc", "odbc", "jdbc"}},
+	{"FileMaker Server", []string{"server", "fms"}},
+}
+
+func getClientType(appVersion string) string {
+	fields := strings.Fields(appVersion)
+	if len(fields) == 0 {
+		return "Other"
+	}
+
+	name := strings.ToLower(fields[0])
+	for _, clientType := range clientTypes {
+		for _, prefix := range clientType.prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return clientType.name
+			}
+		}
+	}
+
+	return "Other"
+}
+
+func getClientTypeSummary(clients []clientInfo) string {
+	counts := map[string]int{}
+	for _, client := range clients {
+		counts[getClientType(client.appVersion)]++
+	}
+
+	var summary []string
+	for _, clientType := range clientTypes {
+		if counts[clientType.name] > 0 {
+			summary = append(summary, clientType.name+": "+strconv.Itoa(counts[clientType.name]))
+		}
+	}
+	if counts["Other"] > 0 {
+		summary = append(summary, "Other: "+strconv.Itoa(counts["Other"]))
+	}
+	if len(summary) == 0 {
+		return strconv.Itoa(len(clients))
+	}
+
+	return strconv.Itoa(len(clients)) + " (" + strings.Join(summary, ", ") + ")"
+}
+
+// showServerStatus prints a summary of the server. Items that can't be
+// retrieved (e.g. while the Database Server is stopped) are shown as "-".
+func showServerStatus(c *cli, baseURI string, token string, usingCloud bool) int {
+	u, _ := url.Parse(baseURI)
+	u.Path = path.Join(getAPIBasePath(), "server", "metadata")
+	versionString, err := getServerVersionString(u.String(), token)
+	if err != nil {
+		fmt.Fprintln(c.outStream, "Admin API:         Not responding")
+		return 10502
+	}
+
+	u.Path = path.Join(getAPIBasePath(), "server", "status")
+	_, running, err := sendRequest("GET", u.String(), token, params{})
+	if err != nil || running == "" {
+		running = "-"
+	}
+
+	files := "-"
+	u.Path = path.Join(getAPIBasePath(), "databases")
+	if databases, exitStatus := getDatabaseRecords(c.outStream, u.String(), token); exitStatus == 0 {
+		opened, paused := 0, 0
+		for _, database := range databases {
+			switch database.Status {
+			case "NORMAL":
+				opened++
+			case "PAUSED":
+				paused++
+			}
+		}
+		files = strconv.Itoa(len(databases)) + " (" + strconv.Itoa(opened) + " open, " + strconv.Itoa(paused) + " paused)"
+	}
+
+	clients := "-"
+	u.Path = path.Join(getAPIBasePath(), "clients")
+	if clientList, exitStatus := getClientInfo(c.outStream, u.String(), token); exitStatus == 0 {
+		clients = getClientTypeSummary(clientList)
+	}
+
+	schedules := "-"
+	u.Path = path.Join(getAPIBasePath(), "schedules")
+	body, _, err := callURL("GET", u.String(), token, nil)
+	var response schedulesResponse
+	if err == nil && decodeResponse(body, &response) == nil && getResultCode(response.Messages) == 0 {
+		var names []string
+		for _, schedule := range response.Response.Schedules {
+			if schedule.Status == "RUNNING" {
+				names = append(names, schedule.Name)
+			}
+		}
+		schedules = strconv.Itoa(len(names))
+		if len(names) > 0 {
+			schedules += " (" + strings.Join(names, ", ") + ")"
+		}
+	}
+
+	blockNewUsers := "-"
+	if isSupported("serverprefs blocknewusersenabled", versionString, usingCloud) {
+		u.Path = path.Join(getAPIBasePath(), "server", "config", "blocknewusers")
+		if enabled, result, err := getServerSettingAsBool(u.String(), token, []string{}); err == nil && result == 0 {
+			blockNewUsers = "No"
+			if enabled {
+				blockNewUsers = "Yes"
+			}
+		}
+	}
+
+	fmt.Fprintln(c.outStream, "Database Server:   "+running)
+	fmt.Fprintln(c.outStream, "Admin API:         Reachable (FileMaker Server "+versionString+")")
+	fmt.Fprintln(c.outStream, "Hosted Files:      "+files)
+	fmt.Fprintln(c.outStream, "Clients:           "+clients)
+	fmt.Fprintln(c.outStream, "Running Schedules: "+schedules)
+	fmt.Fprintln(c.outStream, "Block New Users:   "+blockNewUsers)
+
+	return 0
+}
+
+func listPlugins(c *cli, url string, token string) int {
+	body, _, err := callURL("GET", url, token, nil)
+	if err != nil {
+		fmt.Println(err.Error())
+		return -1
+	}
+
+	var response pluginsResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	result := getResultCode(response.Messages)
+	if result == 1701 {
+		// when fmserverd is stopping
+		return 10502
+	}
+
+	var data [][]string
+
+	if len(response.Response.Plugins) > 0 {
+		for _, plugin := range response.Response.Plugins {
+			status := "Disabled"
+			if plugin.Enabled {
+				status = "Enabled"
+			}
+			data = append(data, []string{string(plugin.ID), plugin.PluginName, plugin.FileName, status})
+		}
+
+		if len(data) > 0 {
+			table := tablewriter.NewWriter(c.outStream)
+			table.SetAutoWrapText(false)
+			table.SetAutoFormatHeaders(false)
+			for _, v := range data {
+				table.SetHeader([]string{"ID", "Name", "File", "Status"})
+				table.Append(v)
+			}
+			table.Render()
+		}
+	}
+
+	return 0
+}
+
+func openDatabases(c *cli, u *url.URL, token string, args []string, key string, saveKeyFlag bool, usingCloud bool) int {
+	exitStatus := 0
+	var err error
+
+	u.Path = path.Join(getAPIBasePath(), "databases")
+	idList, nameList, hintList := getDatabases(u.String(), token, args, "CLOSED", false)
+	if len(idList) > 0 {
+		if usingCloud && (len(key) > 0 || saveKeyFlag) {
+			if len(key) > 0 {
+				exitStatus = outputInvalidOptionErrorMessage(c, "--key")
+			} else {
+				exitStatus = outputInvalidOptionErrorMessage(c, "--savekey")
+			}
+		} else {
+			for i := 0; i < len(idList); i++ {
+				fmt.Fprintln(c.outStream, "File Opening: "+nameList[i])
+			}
+			var result batchResult
+			for i := 0; i < len(idList); i++ {
+				u.Path = path.Join(getAPIBasePath(), "databases", strconv.Itoa(idList[i]))
+				exitStatus, _, err = sendRequest("PATCH", u.String(), token, params{command: "open", key: key, saveKey: saveKeyFlag})
+				if result.add(exitStatus, err) {
+					// Note: FileMaker Admin API does not validate the encryption key.
+					//       You receive a result code of 0 even if you enter an invalid key.
+					var openedID []int
+					for value := 0; ; {
+						value++
+						u.Path = path.Join(getAPIBasePath(), "databases")
+						openedID, _, _ = getDatabases(u.String(), token, []string{strconv.Itoa(idList[i])}, "NORMAL", false)
+						if len(openedID) > 0 || value > 3 {
+							break
+						}
+						timeSleep(1 * time.Second)
+					}
+					if len(openedID) > 0 {
+						fmt.Fprintln(c.outStream, "File Opened: "+nameList[i])
+					} else {
+						fmt.Fprintln(c.outStream, "Fail to open encrypted database. The correct password must be supplied with the --key option. (Hint: "+hintList[i]+")")
+						fmt.Fprintln(c.outStream, "File Closed: "+nameList[i])
+					}
+				}
+			}
+			exitStatus = result.getExitStatus(c)
+		}
+	} else {
+		exitStatus = 10904
+	}
+
+	return exitStatus
+}
+
+// getPlugins returns the IDs and names of the plug-ins specified by ID or name,
+// and the arguments matching no plug-in.
+func getPlugins(url string, token string, arg []string) ([]int, []string, []string) {
+	var idList []int
+	var nameList []string
+	found := make([]bool, len(arg))
+
+	body, _, err := callURL("GET", url, token, nil)
+	if err != nil {
+		fmt.Println(err.Error())
+		return idList, nameList, nil
+	}
+
+	var response pluginsResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	for _, plugin := range response.Response.Plugins {
+		pluginID := string(plugin.ID)
+		pluginName := plugin.PluginName
+		fileName := plugin.FileName
+
+		for j := 0; j < len(arg); j++ {
+			if regexp.MustCompile(`^[0-9]+$`).Match([]byte(arg[j])) {
+				// ID
+				if pluginID != arg[j] {
+					continue
+				}
+			} else {
+				// name
+				name := strings.ToLower(arg[j])
+				if name != strings.ToLower(pluginName) && name != strings.ToLower(fileName) && name != strings.ToLower(strings.TrimSuffix(fileName, filepath.Ext(fileName))) {
+					continue
+				}
+			}
+			found[j] = true
+			id, _ := strconv.Atoi(pluginID)
+			idList = append(idList, id)
+			nameList = append(nameList, pluginName)
+			break
+		}
+	}
+
+	var notFound []string
+	for j := 0; j < len(arg); j++ {
+		if !found[j] {
+			notFound = append(notFound, arg[j])
+		}
+	}
+
+	return idList, nameList, notFound
+}
+
+func changePluginStatus(c *cli, u *url.URL, token string, arg []string, enabled bool) int {
+	exitStatus := 0
+
+	u.Path = path.Join(getAPIBasePath(), "plugins")
+	idList, _, notFound := getPlugins(u.String(), token, arg)
+	for _, name := range notFound {
+		fmt.Fprintln(c.outStream, "fmcsadmin: plug-in not found: "+name)
+	}
+	if len(idList) == 0 || len(notFound) > 0 {
+		return 10007
+	}
+
+	command := "disable"
+	if enabled {
+		command = "enable"
+	}
+	for i := 0; i < len(idList); i++ {
+		u.Path = path.Join(getAPIBasePath(), "plugins", strconv.Itoa(idList[i]))
+		exitStatus, _, _ = sendRequest("PATCH", u.String(), token, params{command: command})
+		if exitStatus != 0 {
+			return exitStatus
+		}
+	}
+
+	u.Path = path.Join(getAPIBasePath(), "plugins")
+	return listPlugins(c, u.String(), token)
+}
+
+func listSchedules(c *cli, urlString string, token string, id int, out outputOptions) int {
+	usingCloud := isCloudURI(urlString)
+
+	body, _, err := callURL("GET", urlString, token, nil)
+	if err != nil {
+		fmt.Println(err.Error())
+		return -1
+	}
+
+	var response schedulesResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	result := getResultCode(response.Messages)
+	if result == 1701 {
+		// when fmserverd is stopping
+		return 10502
+	}
+
+	var data [][]string
+
+	if len(response.Response.Schedules) > 0 {
+		for _, schedule := range response.Response.Schedules {
+			lastRun := ""
+			if schedule.LastRun != nil {
+				lastRun = *schedule.LastRun
+			}
+			nextRun := ""
+			if schedule.NextRun != nil {
+				nextRun = *schedule.NextRun
+			}
+			if !schedule.Enabled {
+				nextRun = "Disabled"
+			}
+			status := schedule.Status
+
+			sID, _ := strconv.Atoi(string(schedule.ID))
+			if id == sID || id == 0 {
+				if status == "IDLE" || status == "RUNNING" {
+					if lastRun == "" || lastRun == "0000-00-00T00:00:00" {
+						status = ""
+					} else {
+						status = "OK"
+					}
+				}
+				lastRun = out.formatDateTime(lastRun, "2006/01/02 15:04", usingCloud)
+				nextRun = out.formatDateTime(nextRun, "2006/01/02 15:04", usingCloud)
+				if out.human {
+					lastRun = getRelativeTimeString(lastRun, out.now())
+					nextRun = getRelativeTimeString(nextRun, out.now())
+				}
+				data = append(data, []string{string(schedule.ID), schedule.Name, schedule.taskType(), lastRun, nextRun, status})
+			}
+		}
+
+		if len(data) > 0 {
+			table := tablewriter.NewWriter(c.outStream)
+			table.SetAutoWrapText(false)
+			table.SetAutoFormatHeaders(false)
+			for _, v := range data {
+				table.SetHeader([]string{"ID", "Name", "Type", "Last Completed", "Next Run", "Status"})
+				table.Append(v)
+			}
+			table.Render()
+		} else {
+			return 10600
+		}
+	}
+
+	return 0
+}
+
+func getScheduleName(url string, token string, id int) string {
+	body, _, err := callURL("GET", url, token, nil)
+	if err != nil {
+		return ""
+	}
+
+	var response schedulesResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		return ""
+	}
+
+	sID, _ := strconv.Atoi(string(response.Response.Schedule.ID))
+	if id == sID || id == 0 {
+		return response.Response.Schedule.Name
+	}
+
+	return ""
+}
+
+func sendMessages(u *url.URL, token string, message string, cmdArgs []string, clientID int) int {
+	var exitStatus int
+
+	args := []string{""}
+	if len(cmdArgs[1:]) > 0 {
+		args = cmdArgs[1:]
+	}
+	u.Path = path.Join(getAPIBasePath(), "clients")
+	idList := getClients(u.String(), token, args)
+	if len(idList) > 0 {
+		for i := 0; i < len(idList); i++ {
+			if clientID == -1 || clientID == idList[i] {
+				u.Path = path.Join(getAPIBasePath(), "clients", strconv.Itoa(idList[i]), "message")
+				exitStatus = sendMessage(u.String(), token, message)
+			}
+			if clientID > 0 {
+				break
+			}
+		}
+	} else {
+		exitStatus = 10904
+	}
+
+	return exitStatus
+}
+
+func sendMessage(url string, token string, message string) int {
+	d := messageInfo{
+		message,
+	}
+	jsonStr, _ := json.Marshal(d)
+	body, _, err := callURL("POST", url, token, bytes.NewBuffer([]byte(jsonStr)))
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	code := -1
+	output := output{}
+	err = json.Unmarshal(body, &output)
+	if err != nil {
+		fmt.Println(err.Error())
+	} else {
+		for i := 0; i < len(output.Messages); i++ {
+			if reflect.ValueOf(output.Messages[i].Code).IsValid() {
+				code, _ = strconv.Atoi(output.Messages[i].Code)
+				break
+			}
+		}
+	}
+
+	return code
+}
+
+var timeNow = time.Now
+var timeSleep = time.Sleep
+
+func getMessageSchedule(at string, repeat string, until string, now time.Time) ([]time.Time, time.Time, string) {
+	var schedule []time.Time
+	var deadline time.Time
+	var interval time.Duration
+	var err error
+
+	start := now
+	if len(at) > 0 {
+		start, err = parseScheduleTime(at, now)
+		if err != nil || start.Before(now.Add(-time.Minute)) {
+			return nil, deadline, "--at"
+		}
+	}
+
+	if len(until) > 0 {
+		deadline, err = parseScheduleTime(until, now)
+		if err != nil || deadline.Before(start) {
+			return nil, deadline, "--until"
+		}
+	}
+
+	if len(repeat) > 0 {
+		interval, err = time.ParseDuration(repeat)
+		if err != nil || interval < time.Minute {
+			return nil, deadline, "--repeat"
+		}
+		if deadline.IsZero() {
+			// don't keep sending messages forever
+			return nil, deadline, "--until"
+		}
+		for t := start; !t.After(deadline); t = t.Add(interval) {
+			schedule = append(schedule, t)
+		}
+	} else {
+		schedule = append(schedule, start)
+	}
+
+	return schedule, deadline, ""
+}
+
+func parseScheduleTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	// relative time (ex.: "+30m")
+	if strings.HasPrefix(value, "+") {
+		d, err := time.ParseDuration(value[1:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006/01/02 15:04:05", "2006/01/02 15:04"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	// time of day (today, or tomorrow when the time has already passed)
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location())
+			if t.Before(now) {
+				t = t.AddDate(0, 0, 1)
+			}
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time: %s", value)
+}
+
+func renderMessage(message string, now time.Time, deadline time.Time) string {
+	if deadline.IsZero() {
+		return message
+	}
+
+	message = strings.ReplaceAll(message, "{remaining}", formatRemainingTime(deadline.Sub(now)))
+	message = strings.ReplaceAll(message, "{until}", deadline.Format("15:04"))
+
+	return message
+}
+
+func formatRemainingTime(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	if minutes <= 0 {
+		return "less than a minute"
+	}
+
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return strconv.Itoa(n) + " " + unit + "s"
+	}
+
+	hours := minutes / 60
+	minutes = minutes % 60
+	if hours == 0 {
+		return plural(minutes, "minute")
+	} else if minutes == 0 {
+		return plural(hours, "hour")
+	}
+
+	return plural(hours, "hour") + " " + plural(minutes, "minute")
+}
+
+func sendScheduledMessages(c *cli, baseURI string, username string, password string, p params, message string, cmdArgs []string, clientID int, schedule []time.Time, deadline time.Time) int {
+	exitStatus := 0
+
+	for i, t := range schedule {
+		if wait := t.Sub(timeNow()); wait > 0 {
+			timeSleep(wait)
+		}
+
+		token, status, err := c.login(baseURI, username, password, p)
+		if token == "" || status != 0 || err != nil {
+			if detectHostUnreachable(status) {
+				status = 10502
+			}
+			return status
+		}
+
+		u, _ := url.Parse(baseURI)
+		exitStatus = sendMessages(u, token, renderMessage(message, timeNow(), deadline), cmdArgs, clientID)
+		c.logout(baseURI, token)
+
+		if exitStatus == 10904 {
+			// no clients are connected at the moment
+			fmt.Fprintln(c.outStream, "No clients to send the message to ("+strconv.Itoa(i+1)+"/"+strconv.Itoa(len(schedule))+").")
+			exitStatus = 0
+		} else if exitStatus != 0 {
+			return exitStatus
+		} else {
+			fmt.Fprintln(c.outStream, "Message sent at "+timeNow().Format("2006/01/02 15:04:05")+" ("+strconv.Itoa(i+1)+"/"+strconv.Itoa(len(schedule))+").")
+		}
+	}
+
+	return exitStatus
+}
+
+func getDatabases(url string, token string, arg []string, status string, fullPath bool) ([]int, []string, []string) {
+	var fileName string
+	var folderName string
+	var idList []int
+	var nameList []string
+	var hintList []string
+	var id int
+
+	body, _, err := callURL("GET", url, token, nil)
+	if err != nil {
+		fmt.Println(err.Error())
+		return idList, nameList, hintList
+	}
+
+	var response databasesResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	for _, database := range response.Response.Databases {
+		fileID := string(database.ID)
+		for j := 0; j < len(arg)+1; j++ {
+			if j == len(arg) && j > 0 {
+				break
+			}
+
+			fileName = ""
+			folderName = ""
+			if len(arg) > 0 {
+				fileName = arg[j]
+				if strings.Contains(fileName, string(os.PathSeparator)) {
+					folderName = fileName
+				}
+			}
+
+			if len(folderName) == 0 {
+				if regexp.MustCompile(`^[0-9]+$`).Match([]byte(arg[j])) {
+					// ID
+					if fileID == arg[j] && (status == database.Status || status == "") {
+						if fullPath {
+							// for "remove" command
+							nameList = append(nameList, database.Folder+database.FileName)
+						} else {
+							nameList = append(nameList, database.FileName)
+						}
+						id, _ = strconv.Atoi(fileID)
+						idList = append(idList, id)
+						hintList = append(hintList, database.DecryptHint)
+					}
+				} else {
+					// name
+					if (fileName == "" || comparePath(fileName, database.FileName)) && (status == database.Status || status == "") {
+						if fullPath {
+							// for "remove" command
+							nameList = append(nameList, database.Folder+database.FileName)
+						} else {
+							nameList = append(nameList, database.FileName)
+						}
+						id, _ = strconv.Atoi(fileID)
+						idList = append(idList, id)
+						hintList = append(hintList, database.DecryptHint)
+					}
+				}
+			} else {
+				if (status == database.Status || status == "") && (comparePath(database.Folder, folderName) || comparePath(database.Folder+database.FileName, fileName)) {
+					if fullPath {
+						// for "remove" command
+						nameList = append(nameList, database.Folder+database.FileName)
+					} else {
+						nameList = append(nameList, database.FileName)
+					}
+					id, _ = strconv.Atoi(fileID)
+					idList = append(idList, id)
+					hintList = append(hintList, database.DecryptHint)
+				}
+			}
+		}
+	}
+
+	if fullPath {
+		// for "remove" command
+		for i := 0; i < len(nameList); i++ {
+			if strings.Index(nameList[i], "filelinux:/") == 0 {
+				nameList[i] = strings.Replace(nameList[i], "filelinux:/", "/", 1)
+			} else if strings.Index(nameList[i], "filewin:/") == 0 {
+				nameList[i] = strings.Replace(strings.Replace(nameList[i], "filewin:/", "", 1), "/", "¥", -1)
+			} else if strings.Index(nameList[i], "filemac:/") == 0 {
+				nameList[i] = strings.Replace(nameList[i], "filemac:/", "/Volumes/", 1)
+			}
+		}
+	}
+
+	return idList, nameList, hintList
+}
+
+func getClients(url string, token string, arg []string) []int {
+	var fileName string
+	var folderName string
+	var idList []int
+	var id int
+
+	body, _, err := callURL("GET", url, token, nil)
+	if err != nil {
+		fmt.Println(err.Error())
+		return idList
+	}
+
+	var response clientsResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	// folders of the hosted databases, retrieved only when a path is given
+	var folders map[string]string
+
+	for i := 0; i < len(arg)+1; i++ {
+		if i == len(arg) && i > 0 {
+			break
+		}
+
+		fileName = ""
+		folderName = ""
+		if len(arg) > 0 {
+			fileName = arg[i]
+			if strings.Contains(fileName, string(os.PathSeparator)) {
+				folderName = fileName
+			}
+		}
+
+		if len(folderName) > 0 && folders == nil {
+			folders = getDatabaseFolders(strings.TrimSuffix(url, "/clients")+"/databases", token)
+		}
+
+		for _, client := range response.Response.Clients {
+			for _, guestFile := range client.GuestFiles {
+				matched := false
+				if len(folderName) == 0 {
+					matched = fileName == "" || comparePath(fileName, guestFile.FileName)
+				} else if directory, ok := folders[string(guestFile.ID)]; ok {
+					matched = comparePath(fileName, directory+guestFile.FileName)
+				}
+				if matched {
+					id, _ = strconv.Atoi(string(client.ID))
+					idList = append(idList, id)
+					break
+				}
+			}
+		}
+	}
+
+	return idList
+}
+
+// getDatabaseFolders returns the folders of the hosted databases by ID
+func getDatabaseFolders(url string, token string) map[string]string {
+	folders := map[string]string{}
+
+	body, _, err := callURL("GET", url, token, nil)
+	if err != nil {
+		return folders
+	}
+
+	var response databasesResponse
+	if decodeResponse(body, &response) != nil {
+		return folders
+	}
+	for _, database := range response.Response.Databases {
+		folders[string(database.ID)] = database.Folder
+	}
+
+	return folders
+}
+
+func getServerGeneralConfigurations(urlString string, token string, printOptions []string) ([]int, int) {
+	var settings []int
+
+	versionString, _ := getServerVersionString(strings.Replace(urlString, "/config/general", "/metadata", 1), token)
+	version, _ := parseServerVersion(versionString)
+
+	body, _, err := callURL("GET", urlString, token, nil)
+	if err != nil {
+		fmt.Println(err.Error())
+		return settings, 10502
+	}
+
+	var response configResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	result := getResultCode(response.Messages)
+	if result == -1 {
+		return settings, 3
+	}
+	cacheSize := response.Response.CacheSize
+	maxFiles := response.Response.MaxFiles
+	maxProConnections := response.Response.MaxProConnections
+	maxPSOS := response.Response.MaxPSOS
+	startupRestorationBuiltin := isSupported("serverprefs startuprestorationenabled", versionString, false)
+	startupRestorationEnabled := false
+	if response.Response.StartupRestorationEnabled != nil {
+		startupRestorationEnabled = *response.Response.StartupRestorationEnabled
+	} else {
+		// for Claris FileMaker Server 19.1.2 or later
+		startupRestorationBuiltin = false
+	}
+	onlyOpenLastOpenedDatabases := false
+
+	settings = append(settings, cacheSize)
+	settings = append(settings, maxFiles)
+	settings = append(settings, maxProConnections)
+	settings = append(settings, maxPSOS)
+	if startupRestorationBuiltin {
+		if startupRestorationEnabled {
+			settings = append(settings, 1)
+		} else {
+			settings = append(settings, 0)
+		}
+	} else {
+		// for Claris FileMaker Server 19.1.2 or later
+		settings = append(settings, -1)
+	}
+
+	if isSupported("serverprefs onlyopenlastopeneddatabases", versionString, false) {
+		// for Claris FileMaker Server 21.1.1 or later
+		if response.Response.OnlyOpenLastOpenedDatabases != nil {
+			onlyOpenLastOpenedDatabases = *response.Response.OnlyOpenLastOpenedDatabases
+		}
+		if onlyOpenLastOpenedDatabases {
+			settings = append(settings, 1)
+		} else {
+			settings = append(settings, 0)
+		}
+	}
+
+	// output
+	if result == 0 {
+		for _, option := range printOptions {
+			if option == "maxguests" {
+				fmt.Println("MaxGuests = " + strconv.Itoa(maxProConnections) + " " + getSettingRangeString(option, version) + " ")
+			}
+			if option == "maxfiles" {
+				fmt.Println("MaxFiles = " + strconv.Itoa(maxFiles) + " " + getSettingRangeString(option, version) + " ")
+			}
+			if option == "cachesize" {
+				fmt.Println("CacheSize = " + strconv.Itoa(cacheSize) + " " + getSettingRangeString(option, version) + " ")
+			}
+			if option == "hostedfiles" {
+				fmt.Println("HostedFiles = " + strconv.Itoa(maxFiles) + " " + getSettingRangeString(option, version) + " ")
+			}
+			if option == "proconnections" {
+				fmt.Println("ProConnections = " + strconv.Itoa(maxProConnections) + " " + getSettingRangeString(option, version) + " ")
+			}
+			if option == "scriptsessions" {
+				fmt.Println("ScriptSessions = " + strconv.Itoa(maxPSOS) + " " + getSettingRangeString(option, version) + " ")
+			} else if option == "allowpsos" {
+				fmt.Println("AllowPSOS = " + strconv.Itoa(maxPSOS) + " " + getSettingRangeString(option, version) + " ")
+			}
+
+			if option == "securefilesonly" || option == "requiresecuredb" {
+				getServerSettingAsBool(strings.Replace(urlString, "/general", "/security", 1), token, []string{option})
+			}
+
+			if startupRestorationBuiltin && option == "startuprestorationenabled" {
+				if startupRestorationEnabled {
+					fmt.Println("StartupRestorationEnabled = true [default: true] ")
+				} else {
+					fmt.Println("StartupRestorationEnabled = false [default: true] ")
+				}
+			}
+
+			if option == "authenticatedstream" {
+				if isSupported("serverprefs authenticatedstream", versionString, false) {
+					getAuthenticatedStreamSetting(strings.Replace(urlString, "/general", "/authenticatedstream", 1), token, []string{option})
+				}
+			}
+
+			if option == "parallelbackupenabled" {
+				if isSupported("serverprefs parallelbackupenabled", versionString, false) {
+					getServerSettingAsBool(strings.Replace(urlString, "/general", "/parallelbackup", 1), token, []string{option})
+				}
+			}
+
+			if option == "persistcacheenabled" || option == "syncpersistcache" {
+				if isSupported("serverprefs persistcacheenabled", versionString, false) {
+					getPersistentCacheConfigurations(strings.Replace(urlString, "/general", "/persistentcache", 1), token, []string{option})
+				}
+			}
+
+			if option == "databaseserverautorestart" {
+				if isSupported("serverprefs databaseserverautorestart", versionString, false) {
+					getPersistentCacheConfigurations(strings.Replace(urlString, "/general", "/persistentcache", 1), token, []string{option})
+				}
+			}
+
+			if option == "blocknewusersenabled" {
+				if isSupported("serverprefs blocknewusersenabled", versionString, false) {
+					getServerSettingAsBool(strings.Replace(urlString, "/general", "/blocknewusers", 1), token, []string{option})
+				}
+			}
+
+			if option == "enablehttpprotocolnetwork" {
+				if isSupported("serverprefs enablehttpprotocolnetwork", versionString, false) {
+					getServerSettingAsBool(strings.Replace(urlString, "/server/config/general", "/fmclients/httpstunneling", 1), token, []string{option})
+				}
+			}
+
+			if option == "onlyopenlastopeneddatabases" {
+				if isSupported("serverprefs onlyopenlastopeneddatabases", versionString, false) {
+					if onlyOpenLastOpenedDatabases {
+						fmt.Println("OnlyOpenLastOpenedDatabases = true [default: false] ")
+					} else {
+						fmt.Println("OnlyOpenLastOpenedDatabases = false [default: false] ")
+					}
 				}
 			}
 		}
 	}
 
-	return idList
+	return settings, result
+}
+
+func getAuthenticatedStreamSetting(urlString string, token string, printOptions []string) (int, int, error) {
+	var authenticatedStream int
+
+	body, _, err := callURL("GET", urlString, token, nil)
+	if err != nil {
+		return 0, 10502, err
+	}
+
+	var response configResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	result := getResultCode(response.Messages)
+	if result == -1 {
+		return 0, 3, errors.New("result code not found")
+	}
+	if response.Response.AuthenticatedStream != nil {
+		authenticatedStream = *response.Response.AuthenticatedStream
+		err = nil
+	} else {
+		err = errors.New("authenticatedStream not found")
+	}
+
+	// output
+	if result == 0 {
+		for _, option := range printOptions {
+			if option == "authenticatedstream" {
+				fmt.Println("AuthenticatedStream = " + strconv.Itoa(authenticatedStream) + " " + getSettingRangeString(option, serverVersion{}) + " ")
+			}
+		}
+	}
+
+	return authenticatedStream, result, err
+}
+
+func getServerSettingAsBool(urlString string, token string, printOptions []string) (bool, int, error) {
+	var enabled bool
+	var enabledStr string
+
+	body, _, err := callURL("GET", urlString, token, nil)
+	if err != nil {
+		return false, 10502, err
+	}
+
+	var response configResponse
+	err = decodeResponse(body, &response)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	result := getResultCode(response.Messages)
+	if result == -1 {
+		return false, 3, errors.New("result code not found")
+	}
+
+	u, err := url.Parse(urlString)
+	if err != nil {
+		return false, 3, err
+	}
+
+	setting := &enabled
+	if u.Path == path.Join(getAPIBasePath(), "server", "config", "security") {
+		setting = response.Response.RequireSecureDB
+	} else if u.Path == path.Join(getAPIBasePath(), "server", "config", "parallelbackup") {
+		setting = response.Response.ParallelBackupEnabled
+	} else if u.Path == path.Join(getAPIBasePath(), "server", "config", "blocknewusers") {
+		setting = response.Response.BlockNewUsers
+	} else if u.Path == path.Join(getAPIBasePath(), "fmclients", "httpstunneling") {
+		setting = response.Response.EnableHTTPSTunneling
+	} else if u.Path == path.Join(getAPIBasePath(), "fmdapi", "config") || u.Path == path.Join(getAPIBasePath(), "odata", "config") || u.Path == path.Join(getAPIBasePath(), "webdirect", "config") {
+		setting = response.Response.Enabled
+	}
+	if setting != nil {
+		enabled = *setting
+	} else {
+		err = errors.New("setting not found")
+	}
+
+	enabledStr = "false"
+	if enabled {
+		enabledStr = "true"
+	}
+
+	// output
+	if result == 0 {
+		for _, option := range printOptions {
+			switch option {
+			case "securefilesonly":
+				fmt.Println("SecureFilesOnly = " + enabledStr + " [default: true] ")
+			case "requiresecuredb":
+				fmt.Println("RequireSecureDB = " + enabledStr + " [default: true] ")
+			case "parallelbackupenabled":
+				fmt.Println("ParallelBackupEnabled = " + enabledStr + " [default: false] ")
+			case "blocknewusersenabled":
+				fmt.Println("BlockNewUsersEnabled = " + enabledStr + " [default: false] ")
+			case "enablehttpprotocolnetwork":
+				fmt.Println("EnableHttpProtocolNetwork = " + enabledStr + " [default: false] ")
+			case "onlyopenlastopeneddatabases":
+				fmt.Println("OnlyOpenLastOpenedDatabases = " + enabledStr + " [default: false] ")
+			default:
+			}
+		}
+	}
+
+	return enabled, result, err
+}
+
+func getWebTechnologyConfigurations(baseURI string, basePath string, token string, printOptions []string) ([]string, int, error) {
+	var settings []string
+	var result int
+	var enabledPhpStr string
+	var enabledXMLStr string
+	var dataPreValidationStr string
+	var useFileMakerPhpStr string
+
+	// get PHP Technology Configuration
+	u, _ := url.Parse(baseURI)
+	u.Path = path.Join(basePath, "php", "config")
+
+	body, exitStatus, err := callURL("GET", u.String(), token, nil)
+	if err != nil {
+		fmt.Println(err.Error())
+		return settings, 10502, err
+	}
+
+	var phpResponse configResponse
+	err = decodeResponse(body, &phpResponse)
+	if err != nil {
+		return settings, 3, err
+	}
+
+	if getResultCode(phpResponse.Messages) == -1 {
+		return settings, 3, errors.New("result code not found")
+	}
+	enabledPhp := phpResponse.Response.Enabled != nil && *phpResponse.Response.Enabled
+	characterEncoding := phpResponse.Response.CharacterEncoding
+	errorMessageLanguage := phpResponse.Response.ErrorMessageLanguage
+	dataPreValidation := phpResponse.Response.DataPreValidation
+	useFileMakerPhp := phpResponse.Response.UseFileMakerPhp
+
+	enabledPhpStr = "true"
+	if !enabledPhp {
+		enabledPhpStr = "false"
+	}
+
+	if exitStatus == 500 {
+		// for Claris FileMaker Server for Linux
+		dataPreValidationStr = ""
+		useFileMakerPhpStr = "true"
+	} else {
+		dataPreValidationStr = "true"
+		if !dataPreValidation {
+			dataPreValidationStr = "false"
+		}
+
+		useFileMakerPhpStr = "true"
+		if !useFileMakerPhp {
+			useFileMakerPhpStr = "false"
+		}
+	}
+
+	// get XML Technology Configuration
+	u.Path = path.Join(basePath, "xml", "config")
+
+	body, _, err = callURL("GET", u.String(), token, nil)
+	if err != nil {
+		fmt.Println(err.Error())
+		return settings, -1, err
+	}
+
+	var xmlResponse configResponse
+	err = decodeResponse(body, &xmlResponse)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	result = getResultCode(xmlResponse.Messages)
+	if result == -1 {
+		return settings, 3, errors.New("result code not found")
+	}
+	enabledXML := false
+	if xmlResponse.Response.Enabled != nil {
+		enabledXML = *xmlResponse.Response.Enabled
+	} else {
+		err = errors.New("enabled not found")
+	}
+
+	enabledXMLStr = "true"
+	if !enabledXML {
+		enabledXMLStr = "false"
+	}
+
+	settings = append(settings, enabledPhpStr)
+	settings = append(settings, enabledXMLStr)
+	settings = append(settings, characterEncoding)
+	settings = append(settings, errorMessageLanguage)
+	settings = append(settings, dataPreValidationStr)
+	settings = append(settings, useFileMakerPhpStr)
+
+	// output
+	if result == 0 {
+		for _, option := range printOptions {
+			if option == "enablephp" {
+				fmt.Println("EnablePHP = " + enabledPhpStr)
+			}
+			if option == "enablexml" {
+				fmt.Println("EnableXML = " + enabledXMLStr)
+			}
+			if option == "encoding" {
+				fmt.Println("Encoding = " + characterEncoding + " [ UTF-8 ISO-8859-1 ]")
+			}
+			if option == "locale" {
+				fmt.Println("Locale = " + errorMessageLanguage + " [ en de fr it ja ]")
+			}
+			if option == "prevalidation" {
+				fmt.Println("PreValidation = " + dataPreValidationStr)
+			}
+			if option == "usefmphp" {
+				fmt.Println("UseFMPHP = " + useFileMakerPhpStr)
+			}
+		}
+	}
+
+	return settings, result, err
 }
 
-func getServerGeneralConfigurations(urlString string, token string, printOptions []string) ([]int, int) {
-	var settings []int
-	var resultCode string
-	var result int
-	var cacheSize int
-	var maxFiles int
-	var maxProConnections int
-	var maxPSOS int
-	var startupRestorationEnabled bool
-	var onlyOpenLastOpenedDatabases bool
+// connectorSetting is a setting of GET/SET CONNECTORCONFIG. Each connector has
+// its own endpoint with the "enabled" property.
+type connectorSetting struct {
+	name     string
+	label    string
+	endpoint string
+}
 
-	versionString, _ := getServerVersionString(strings.Replace(urlString, "/config/general", "/metadata", 1), token)
-	version, _ := getServerVersionAsFloat(versionString)
+var connectorSettings = []connectorSetting{
+	{name: "enabledataapi", label: "EnableDataAPI", endpoint: "fmdapi/config"},
+	{name: "enableodata", label: "EnableOData", endpoint: "odata/config"},
+	{name: "enablewebdirect", label: "EnableWebDirect", endpoint: "webdirect/config"},
+}
 
-	body, _, err := callURL("GET", urlString, token, nil)
+func getConnectorSetting(name string) (connectorSetting, bool) {
+	for _, setting := range connectorSettings {
+		if setting.name == strings.ToLower(name) {
+			return setting, true
+		}
+	}
+
+	return connectorSetting{}, false
+}
+
+// getConnectorConfigurations prints the connector settings of names. Settings
+// the server doesn't support are skipped unless explicit is true.
+func getConnectorConfigurations(c *cli, baseURI string, token string, names []string, explicit bool) int {
+	u, _ := url.Parse(baseURI)
+	u.Path = path.Join(getAPIBasePath(), "server", "metadata")
+	versionString, err := getServerVersionString(u.String(), token)
 	if err != nil {
-		fmt.Println(err.Error())
-		return settings, 10502
+		return 10502
+	}
+
+	for _, name := range names {
+		setting, _ := getConnectorSetting(name)
+		if !isSupported("connectorconfig "+setting.name, versionString, false) {
+			if explicit {
+				outputCapabilityErrorMessage(c, "connectorconfig "+setting.name, versionString, false)
+				return 10001
+			}
+			continue
+		}
+
+		u.Path = path.Join(getAPIBasePath(), setting.endpoint)
+		enabled, result, err := getServerSettingAsBool(u.String(), token, []string{})
+		if err != nil && result == 0 {
+			result = 3
+		}
+		if result != 0 {
+			return result
+		}
+		// the defaults of the connectors depend on how the server was installed
+		fmt.Fprintln(c.outStream, setting.label+" = "+strconv.FormatBool(enabled)+" ")
+	}
+
+	return 0
+}
+
+// setConnectorConfigurations changes the connector settings of the NAME=VALUE
+// arguments and prints the new values.
+func setConnectorConfigurations(c *cli, baseURI string, token string, args []string) int {
+	results, exitStatus := parseConnectorConfigurationSettings(args)
+	if exitStatus != 0 {
+		return exitStatus
 	}
 
-	var v interface{}
-	err = json.Unmarshal(body, &v)
+	u, _ := url.Parse(baseURI)
+	u.Path = path.Join(getAPIBasePath(), "server", "metadata")
+	versionString, err := getServerVersionString(u.String(), token)
 	if err != nil {
-		fmt.Println(err.Error())
+		return 10502
+	}
+
+	var names []string
+	var changes []settingChange
+	for i, setting := range connectorSettings {
+		if results[i] == "" {
+			continue
+		}
+		if !isSupported("connectorconfig "+setting.name, versionString, false) {
+			outputCapabilityErrorMessage(c, "connectorconfig "+setting.name, versionString, false)
+			return 10001
+		}
+		names = append(names, setting.name)
+		changes = append(changes, newSettingChange(setting.endpoint, args, params{command: "set", enabled: results[i]}))
+	}
+
+	exitStatus = applySettingChanges(c, baseURI, token, changes)
+	if exitStatus != 0 {
+		return exitStatus
 	}
 
-	/* for debugging */
-	//fmt.Println(bytes.NewBuffer([]byte(body)))
+	return getConnectorConfigurations(c, baseURI, token, names, true)
+}
+
+func getPersistentCacheConfigurations(urlString string, token string, printOptions []string) ([]string, int, error) {
+	var settings []string
+
+	persistentCacheStr := "false"
+	persistentCacheSyncStr := "false"
+	databaseServerAutoRestartStr := "false"
 
-	err = scan.ScanTree(v, "/messages[0]/code", &resultCode)
+	body, _, err := callURL("GET", urlString, token, nil)
 	if err != nil {
-		return settings, 3
+		return settings, 10502, err
 	}
-	result, _ = strconv.Atoi(resultCode)
-	_ = scan.ScanTree(v, "/response/cacheSize", &cacheSize)
-	_ = scan.ScanTree(v, "/response/maxFiles", &maxFiles)
-	_ = scan.ScanTree(v, "/response/maxProConnections", &maxProConnections)
-	_ = scan.ScanTree(v, "/response/maxPSOS", &maxPSOS)
-	startupRestorationBuiltin := true
-	err = scan.ScanTree(v, "/response/startupRestorationEnabled", &startupRestorationEnabled)
+
+	var response configResponse
+	err = decodeResponse(body, &response)
 	if err != nil {
-		// for Claris FileMaker Server 19.1.2 or later
-		startupRestorationBuiltin = false
+		fmt.Println(err.Error())
 	}
 
-	settings = append(settings, cacheSize)
-	settings = append(settings, maxFiles)
-	settings = append(settings, maxProConnections)
-	settings = append(settings, maxPSOS)
-	if startupRestorationBuiltin {
-		if startupRestorationEnabled {
-			settings = append(settings, 1)
-		} else {
-			settings = append(settings, 0)
-		}
-	} else {
-		// for Claris FileMaker Server 19.1.2 or later
-		settings = append(settings, -1)
+	result := getResultCode(response.Messages)
+	if result == -1 {
+		return settings, 3, errors.New("result code not found")
 	}
+	persistentCache := response.Response.PersistentCache
+	persistentCacheSync := response.Response.PersistentCacheSync
+	databaseServerAutoRestart := response.Response.DatabaseServerAutoRestart
 
-	if version >= 21.1 {
-		// for Claris FileMaker Server 21.1.1 or later
-		_ = scan.ScanTree(v, "/response/onlyOpenLastOpenedDatabases", &onlyOpenLastOpenedDatabases)
-		if onlyOpenLastOpenedDatabases {
-			settings = append(settings, 1)
-		} else {
-			settings = append(settings, 0)
-		}
+	if persistentCache {
+		persistentCacheStr = "true"
+	}
+	if persistentCacheSync {
+		persistentCacheSyncStr = "true"
+	}
+	if databaseServerAutoRestart {
+		databaseServerAutoRestartStr = "true"
 	}
 
+	settings = append(settings, persistentCacheStr)
+	settings = append(settings, persistentCacheSyncStr)
+	settings = append(settings, databaseServerAutoRestartStr)
+
 	// output
 	if result == 0 {
 		for _, option := range printOptions {
-			if option == "maxguests" {
-				fmt.Println("MaxGuests = " + strconv.Itoa(maxProConnections) + " [default: 250, range: 0-2000] ")
-			}
-			if option == "maxfiles" {
-				if version >= 20.1 {
-					fmt.Println("MaxFiles = " + strconv.Itoa(maxFiles) + " [default: 256, range: 1-256] ")
-				} else {
-					fmt.Println("MaxFiles = " + strconv.Itoa(maxFiles) + " [default: 125, range: 1-125] ")
-				}
+			if option == "persistcacheenabled" {
+				fmt.Println("PersistCacheEnabled = " + persistentCacheStr + " [default: false] ")
 			}
-			if option == "cachesize" {
-				fmt.Println("CacheSize = " + strconv.Itoa(cacheSize) + " [default: 512, range: 64-1048576] ")
+			if option == "syncpersistcache" {
+				fmt.Println("SyncPersistCache = " + persistentCacheSyncStr + " [default: false] ")
 			}
-			if option == "hostedfiles" {
-				if version >= 20.1 {
-					fmt.Println("HostedFiles = " + strconv.Itoa(maxFiles) + " [default: 256, range: 1-256] ")
-				} else {
-					fmt.Println("HostedFiles = " + strconv.Itoa(maxFiles) + " [default: 125, range: 1-125] ")
-				}
+			if option == "databaseserverautorestart" {
+				fmt.Println("DatabaseServerAutoRestart = " + databaseServerAutoRestartStr + " [default: false] ")
 			}
-			if option == "proconnections" {
-				fmt.Println("ProConnections = " + strconv.Itoa(maxProConnections) + " [default: 250, range: 0-2000] ")
+		}
+	}
+
+	return settings, result, err
+}
+
+func disconnectAllClient(u *url.URL, token string, message string, graceTime int) (int, error) {
+	exitStatus := 0
+	var err error
+
+	// check the client connection
+	u.Path = path.Join(getAPIBasePath(), "clients")
+	idList := getClients(u.String(), token, []string{""})
+
+	// disconnect clients
+	if len(idList) > 0 {
+		for i := 0; i < len(idList); i++ {
+			u.Path = path.Join(getAPIBasePath(), "clients", strconv.Itoa(idList[i]))
+			u.RawQuery = "messageText=" + url.QueryEscape(message) + "&graceTime=" + url.QueryEscape(strconv.Itoa(graceTime))
+			exitStatus, _, err = sendRequest("DELETE", u.String(), token, params{command: "disconnect"})
+			if err != nil {
+				break
 			}
-			if option == "scriptsessions" {
-				fmt.Println("ScriptSessions = " + strconv.Itoa(maxPSOS) + " [default: 100, range: 0-500] ")
-			} else if option == "allowpsos" {
-				fmt.Println("AllowPSOS = " + strconv.Itoa(maxPSOS) + " [default: 100, range: 0-500] ")
+		}
+	}
+
+	return exitStatus, err
+}
+
+func stopDatabaseServer(u *url.URL, token string, message string, graceTime int) (int, error) {
+	exitStatus := -1
+	forceFlag := false
+	var err error
+
+	// disconnect clients
+	_, _ = disconnectAllClient(u, token, message, graceTime)
+
+	// close databases
+	u.Path = path.Join(getAPIBasePath(), "databases")
+	idList, _, _ := getDatabases(u.String(), token, []string{""}, "NORMAL", false)
+	if len(idList) > 0 {
+		for i := 0; i < len(idList); i++ {
+			u.Path = path.Join(getAPIBasePath(), "databases", strconv.Itoa(idList[i]))
+			if graceTime == 0 {
+				forceFlag = true
 			}
+			_, _, _ = sendRequest("PATCH", u.String(), token, params{command: "close", messageText: message, force: forceFlag})
+		}
+	}
+
+	var openedID []int
+	for value := 0; ; {
+		timeSleep(1 * time.Second)
+		value++
+		u.Path = path.Join(getAPIBasePath(), "databases")
+		openedID, _, _ = getDatabases(u.String(), token, []string{""}, "CLOSING", false)
+		if len(openedID) == 0 || value > 120 {
+			break
+		}
+	}
+
+	// stop database server
+	u.Path = path.Join(getAPIBasePath(), "server", "status")
+	exitStatus, _, err = sendRequest("PATCH", u.String(), token, params{status: "STOPPED"})
+
+	return exitStatus, err
+}
+
+// errWaitTimeout is returned when the server does not reach the expected state in time.
+var errWaitTimeout = errors.New("timed out")
+
+func waitStoppingServer(u *url.URL, token string) (int, error) {
+	exitStatus := 0
+	var err error
+	var running string
+
+	for value := 0; ; {
+		timeSleep(1 * time.Second)
+		value++
+		u.Path = path.Join(getAPIBasePath(), "server", "status")
+		exitStatus, running, err = sendRequest("GET", u.String(), token, params{})
+		if running == "STOPPED" {
+			break
+		}
+		if value > 120 {
+			return exitStatus, errWaitTimeout
+		}
+	}
+
+	return exitStatus, err
+}
+
+// timedOut prints that the command gave up waiting for the server and returns the error code.
+// The process exit status is exitStatusTimeout in the mapped exit code mode.
+func (c *cli) timedOut(what string) int {
+	fmt.Fprintln(c.outStream, "fmcsadmin: Timed out waiting for "+what)
+	c.failure = exitStatusTimeout
+
+	return 10001
+}
+
+// batchResult is the result of a command applied to several databases.
+type batchResult struct {
+	succeeded  int
+	exitStatus int
+}
+
+// add records the result of the request for one database and reports whether it succeeded.
+func (r *batchResult) add(exitStatus int, err error) bool {
+	if exitStatus == 0 && err == nil {
+		r.succeeded++
+		return true
+	}
+	r.exitStatus = exitStatus
+	if r.exitStatus == 0 {
+		r.exitStatus = -1
+	}
+
+	return false
+}
+
+// getExitStatus returns the error code of the last failed request, or 0.
+// The process exit status is exitStatusPartialFailure in the mapped exit code mode
+// when the command succeeded for some of the databases.
+func (r *batchResult) getExitStatus(c *cli) int {
+	if r.exitStatus != 0 && r.succeeded > 0 {
+		c.failure = exitStatusPartialFailure
+	}
+
+	return r.exitStatus
+}
+
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+	checkUnknown  = 3
+)
+
+var checkStateNames = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+type checkThresholds struct {
+	clientsWarning  int
+	clientsCritical int
+	certWarning     int
+	certCritical    int
+}
+
+type checkResult struct {
+	state    int
+	problems []string
+	summary  []string
+	perfdata []string
+}
+
+func (r *checkResult) add(state int, message string) {
+	// CRITICAL > WARNING > UNKNOWN > OK
+	severity := []int{0, 2, 3, 1}
+	if severity[state] > severity[r.state] {
+		r.state = state
+	}
+	if state == checkOK {
+		r.summary = append(r.summary, message)
+	} else {
+		r.problems = append(r.problems, message)
+	}
+}
 
-			if option == "securefilesonly" || option == "requiresecuredb" {
-				getServerSettingAsBool(strings.Replace(urlString, "/general", "/security", 1), token, []string{option})
-			}
+func (r *checkResult) output(c *cli) int {
+	messages := r.problems
+	if len(messages) == 0 {
+		messages = r.summary
+	}
+	line := "FMCSADMIN " + checkStateNames[r.state] + " - " + strings.Join(messages, ", ")
+	if len(r.perfdata) > 0 {
+		line = line + " | " + strings.Join(r.perfdata, " ")
+	}
+	fmt.Fprintln(c.outStream, line)
 
-			if startupRestorationBuiltin && option == "startuprestorationenabled" {
-				if startupRestorationEnabled {
-					fmt.Println("StartupRestorationEnabled = true [default: true] ")
-				} else {
-					fmt.Println("StartupRestorationEnabled = false [default: true] ")
-				}
-			}
+	return r.state
+}
 
-			if option == "authenticatedstream" {
-				if version >= 19.3 && !strings.HasPrefix(versionString, "19.3.1") {
-					getAuthenticatedStreamSetting(strings.Replace(urlString, "/general", "/authenticatedstream", 1), token, []string{option})
-				}
-			}
+func formatThreshold(value int) string {
+	if value < 0 {
+		return ""
+	}
 
-			if option == "parallelbackupenabled" {
-				if version >= 19.5 {
-					getServerSettingAsBool(strings.Replace(urlString, "/general", "/parallelbackup", 1), token, []string{option})
-				}
-			}
+	return strconv.Itoa(value)
+}
 
-			if option == "persistcacheenabled" || option == "syncpersistcache" {
-				if version >= 20.1 {
-					getPersistentCacheConfigurations(strings.Replace(urlString, "/general", "/persistentcache", 1), token, []string{option})
-				}
-			}
+func getThresholdState(value int, warning int, critical int) int {
+	if critical >= 0 && value > critical {
+		return checkCritical
+	} else if warning >= 0 && value > warning {
+		return checkWarning
+	}
 
-			if option == "databaseserverautorestart" {
-				if version >= 21.0 {
-					getPersistentCacheConfigurations(strings.Replace(urlString, "/general", "/persistentcache", 1), token, []string{option})
-				}
-			}
+	return checkOK
+}
 
-			if option == "blocknewusersenabled" {
-				if version >= 21.0 {
-					getServerSettingAsBool(strings.Replace(urlString, "/general", "/blocknewusers", 1), token, []string{option})
-				}
-			}
+func getCertificateState(days int, warning int, critical int) int {
+	if days <= critical {
+		return checkCritical
+	} else if days <= warning {
+		return checkWarning
+	}
 
-			if option == "enablehttpprotocolnetwork" {
-				if version >= 21.1 {
-					getServerSettingAsBool(strings.Replace(urlString, "/server/config/general", "/fmclients/httpstunneling", 1), token, []string{option})
-				}
-			}
+	return checkOK
+}
 
-			if option == "onlyopenlastopeneddatabases" {
-				if version >= 21.1 {
-					if onlyOpenLastOpenedDatabases {
-						fmt.Println("OnlyOpenLastOpenedDatabases = true [default: false] ")
-					} else {
-						fmt.Println("OnlyOpenLastOpenedDatabases = false [default: false] ")
-					}
-				}
-			}
-		}
+func getCertificateExpiry(address string, serverName string) (time.Time, error) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	// only the expiry date is inspected, so the certificate chain is not verified here
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	if err != nil {
+		return time.Time{}, err
 	}
+	defer conn.Close()
 
-	return settings, result
+	certificates := conn.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return time.Time{}, errors.New("no certificate")
+	}
+
+	return certificates[0].NotAfter, nil
 }
 
-func getAuthenticatedStreamSetting(urlString string, token string, printOptions []string) (int, int, error) {
-	var resultCode string
-	var result int
-	var authenticatedStream int
+func checkServer(c *cli, baseURI string, token string, files []string, thresholds checkThresholds, usingCloud bool) int {
+	result := &checkResult{}
+	u, _ := url.Parse(baseURI)
 
-	body, _, err := callURL("GET", urlString, token, nil)
+	// Admin API
+	u.Path = path.Join(getAPIBasePath(), "server", "metadata")
+	versionString, err := getServerVersionString(u.String(), token)
 	if err != nil {
-		return 0, 10502, err
+		result.add(checkCritical, "Admin API is not responding")
+		return result.output(c)
 	}
+	result.add(checkOK, "version "+versionString)
 
-	var v interface{}
-	err = json.Unmarshal(body, &v)
-	if err != nil {
-		fmt.Println(err.Error())
+	// Database Server
+	u.Path = path.Join(getAPIBasePath(), "server", "status")
+	_, running, err := sendRequest("GET", u.String(), token, params{})
+	if err != nil || running == "" {
+		result.add(checkUnknown, "could not get the status of the database server")
+	} else if running != "RUNNING" {
+		result.add(checkCritical, "database server is "+running)
+	} else {
+		result.add(checkOK, "database server is RUNNING")
 	}
 
-	err = scan.ScanTree(v, "/messages[0]/code", &resultCode)
-	if err != nil {
-		return 0, 3, err
+	// databases
+	u.Path = path.Join(getAPIBasePath(), "databases")
+	for _, file := range files {
+		if idList, _, _ := getDatabases(u.String(), token, []string{file}, "", false); len(idList) == 0 {
+			result.add(checkCritical, file+" is not hosted")
+		} else if idList, _, _ := getDatabases(u.String(), token, []string{file}, "NORMAL", false); len(idList) == 0 {
+			result.add(checkCritical, file+" is not open")
+		}
 	}
-	result, _ = strconv.Atoi(resultCode)
-	err = scan.ScanTree(v, "/response/authenticatedStream", &authenticatedStream)
+	idList, _, _ := getDatabases(u.String(), token, []string{""}, "NORMAL", false)
+	result.add(checkOK, strconv.Itoa(len(idList))+" files open")
+	result.perfdata = append(result.perfdata, "files="+strconv.Itoa(len(idList))+";;;0")
 
-	// output
-	if result == 0 {
-		for _, option := range printOptions {
-			if option == "authenticatedstream" {
-				fmt.Println("AuthenticatedStream = " + strconv.Itoa(authenticatedStream) + " [default: 1, range: 1-2] ")
+	// clients
+	u.Path = path.Join(getAPIBasePath(), "clients")
+	// the output of CHECK is a single line
+	clients, exitStatus := getClientInfo(io.Discard, u.String(), token)
+	if exitStatus != 0 {
+		result.add(checkUnknown, "could not get the clients")
+	} else {
+		state := getThresholdState(len(clients), thresholds.clientsWarning, thresholds.clientsCritical)
+		result.add(state, strconv.Itoa(len(clients))+" clients")
+		result.perfdata = append(result.perfdata, "clients="+strconv.Itoa(len(clients))+";"+formatThreshold(thresholds.clientsWarning)+";"+formatThreshold(thresholds.clientsCritical)+";0")
+	}
+
+	// schedules
+	u.Path = path.Join(getAPIBasePath(), "schedules")
+	body, _, err := callURL("GET", u.String(), token, nil)
+	var response schedulesResponse
+	if err != nil || decodeResponse(body, &response) != nil {
+		result.add(checkUnknown, "could not get the schedules")
+	} else {
+		for _, schedule := range response.Response.Schedules {
+			status := schedule.Status
+			if status != "" && status != "IDLE" && status != "RUNNING" {
+				result.add(checkWarning, "schedule \""+schedule.Name+"\" is "+status)
 			}
 		}
 	}
 
-	return authenticatedStream, result, err
+	// certificate
+	if !usingCloud && (thresholds.certWarning > 0 || thresholds.certCritical > 0) {
+		host := u.Hostname()
+		if host == "127.0.0.1" {
+			host = "localhost"
+		}
+		notAfter, err := getCertificateExpiry(getCertificateAddress(u), host)
+		if err != nil {
+			result.add(checkUnknown, "could not get the certificate")
+		} else {
+			days := int(notAfter.Sub(timeNow()).Hours() / 24)
+			state := getCertificateState(days, thresholds.certWarning, thresholds.certCritical)
+			result.add(state, "certificate expires in "+strconv.Itoa(days)+" days")
+			result.perfdata = append(result.perfdata, "cert_days="+strconv.Itoa(days)+";"+strconv.Itoa(thresholds.certWarning)+";"+strconv.Itoa(thresholds.certCritical))
+		}
+	}
+
+	return result.output(c)
 }
 
-func getServerSettingAsBool(urlString string, token string, printOptions []string) (bool, int, error) {
-	var resultCode string
-	var result int
-	var enabled bool
-	var enabledStr string
+// getCertificateAddress returns the address of the HTTPS server to check the
+// certificate of. The local server is checked on the default HTTPS port because
+// the Admin API is called over HTTP on another port.
+func getCertificateAddress(u *url.URL) string {
+	port := u.Port()
+	if u.Scheme != "https" || port == "" {
+		port = "443"
+	}
 
-	body, _, err := callURL("GET", urlString, token, nil)
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+type lintPolicy struct {
+	Rules []lintRule `yaml:"rules"`
+}
+
+// lintRule is a rule of a policy file. A rule has either "equals" or "min"
+// and/or "max".
+type lintRule struct {
+	Name               string      `yaml:"name"`
+	Setting            string      `yaml:"setting"`
+	Equals             interface{} `yaml:"equals"`
+	Min                *int        `yaml:"min"`
+	Max                *int        `yaml:"max"`
+	OutsideMaintenance bool        `yaml:"outside_maintenance"`
+}
+
+// lintSettings are the settings that can be used in a policy file. "encrypted"
+// is true when all hosted databases are encrypted.
+var lintSettings = []string{
+	"cachesize", "maxfiles", "maxguests", "allowpsos", "startuprestorationenabled", "onlyopenlastopeneddatabases",
+	"securefilesonly", "authenticatedstream", "parallelbackupenabled", "blocknewusersenabled", "enablehttpprotocolnetwork",
+	"persistcacheenabled", "syncpersistcache", "databaseserverautorestart",
+	"enablephp", "enablexml", "encoding", "locale", "prevalidation", "usefmphp",
+	"encrypted",
+}
+
+func readLintPolicy(fileName string) (lintPolicy, error) {
+	var policy lintPolicy
+
+	data, err := os.ReadFile(fileName)
 	if err != nil {
-		return false, 10502, err
+		return policy, err
 	}
 
-	var v interface{}
-	err = json.Unmarshal(body, &v)
-	if err != nil {
-		fmt.Println(err.Error())
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&policy); err != nil && err != io.EOF {
+		return policy, err
 	}
 
-	err = scan.ScanTree(v, "/messages[0]/code", &resultCode)
-	if err != nil {
-		return false, 3, err
+	for i, rule := range policy.Rules {
+		rule.Setting = strings.ToLower(rule.Setting)
+		if !slices.Contains(lintSettings, rule.Setting) {
+			return policy, fmt.Errorf("rule %d: unknown setting %q", i+1, rule.Setting)
+		}
+		if rule.Equals == nil && rule.Min == nil && rule.Max == nil {
+			return policy, fmt.Errorf("rule %d: %s has no condition", i+1, rule.Setting)
+		}
+		policy.Rules[i] = rule
 	}
-	result, _ = strconv.Atoi(resultCode)
 
-	u, err := url.Parse(urlString)
-	if err != nil {
-		return false, 3, err
+	return policy, nil
+}
+
+// getServerSettingValues reads the settings of the server through the getters
+// used by the GET command. A setting the server doesn't provide is absent.
+func getServerSettingValues(baseURI string, token string) map[string]string {
+	values := map[string]string{}
+	u, _ := url.Parse(baseURI)
+
+	u.Path = path.Join(getAPIBasePath(), "server", "config", "general")
+	if settings, result := getServerGeneralConfigurations(u.String(), token, []string{}); result == 0 && len(settings) > 4 {
+		values["cachesize"] = strconv.Itoa(settings[0])
+		values["maxfiles"] = strconv.Itoa(settings[1])
+		values["maxguests"] = strconv.Itoa(settings[2])
+		values["allowpsos"] = strconv.Itoa(settings[3])
+		if settings[4] != -1 {
+			values["startuprestorationenabled"] = strconv.FormatBool(settings[4] == 1)
+		}
+		if len(settings) > 5 {
+			values["onlyopenlastopeneddatabases"] = strconv.FormatBool(settings[5] == 1)
+		}
 	}
 
-	if u.Path == path.Join(getAPIBasePath(), "server", "config", "security") {
-		err = scan.ScanTree(v, "/response/requireSecureDB", &enabled)
-	} else if u.Path == path.Join(getAPIBasePath(), "server", "config", "parallelbackup") {
-		err = scan.ScanTree(v, "/response/parallelBackupEnabled", &enabled)
-	} else if u.Path == path.Join(getAPIBasePath(), "server", "config", "blocknewusers") {
-		err = scan.ScanTree(v, "/response/blockNewUsers", &enabled)
-	} else if u.Path == path.Join(getAPIBasePath(), "fmclients", "httpstunneling") {
-		err = scan.ScanTree(v, "/response/enableHTTPSTunneling", &enabled)
+	boolSettings := []struct {
+		name     string
+		elements []string
+	}{
+		{"securefilesonly", []string{"server", "config", "security"}},
+		{"parallelbackupenabled", []string{"server", "config", "parallelbackup"}},
+		{"blocknewusersenabled", []string{"server", "config", "blocknewusers"}},
+		{"enablehttpprotocolnetwork", []string{"fmclients", "httpstunneling"}},
+	}
+	for _, setting := range boolSettings {
+		u.Path = path.Join(append([]string{getAPIBasePath()}, setting.elements...)...)
+		if enabled, result, err := getServerSettingAsBool(u.String(), token, []string{}); result == 0 && err == nil {
+			values[setting.name] = strconv.FormatBool(enabled)
+		}
 	}
 
-	enabledStr = "false"
-	if enabled {
-		enabledStr = "true"
+	u.Path = path.Join(getAPIBasePath(), "server", "config", "authenticatedstream")
+	if authenticatedStream, result, err := getAuthenticatedStreamSetting(u.String(), token, []string{}); result == 0 && err == nil {
+		values["authenticatedstream"] = strconv.Itoa(authenticatedStream)
 	}
 
-	// output
-	if result == 0 {
-		for _, option := range printOptions {
-			switch option {
-			case "securefilesonly":
-				fmt.Println("SecureFilesOnly = " + enabledStr + " [default: true] ")
-			case "requiresecuredb":
-				fmt.Println("RequireSecureDB = " + enabledStr + " [default: true] ")
-			case "parallelbackupenabled":
-				fmt.Println("ParallelBackupEnabled = " + enabledStr + " [default: false] ")
-			case "blocknewusersenabled":
-				fmt.Println("BlockNewUsersEnabled = " + enabledStr + " [default: false] ")
-			case "enablehttpprotocolnetwork":
-				fmt.Println("EnableHttpProtocolNetwork = " + enabledStr + " [default: false] ")
-			case "onlyopenlastopeneddatabases":
-				fmt.Println("OnlyOpenLastOpenedDatabases = " + enabledStr + " [default: false] ")
-			default:
+	u.Path = path.Join(getAPIBasePath(), "server", "config", "persistentcache")
+	if settings, result, err := getPersistentCacheConfigurations(u.String(), token, []string{}); result == 0 && err == nil && len(settings) > 2 {
+		values["persistcacheenabled"] = settings[0]
+		values["syncpersistcache"] = settings[1]
+		values["databaseserverautorestart"] = settings[2]
+	}
+
+	if settings, result, err := getWebTechnologyConfigurations(baseURI, getAPIBasePath(), token, []string{}); result == 0 && err == nil && len(settings) > 5 {
+		for i, name := range []string{"enablephp", "enablexml", "encoding", "locale", "prevalidation", "usefmphp"} {
+			if len(settings[i]) > 0 {
+				values[name] = settings[i]
 			}
 		}
 	}
 
-	return enabled, result, err
+	return values
 }
 
-func getWebTechnologyConfigurations(baseURI string, basePath string, token string, printOptions []string) ([]string, int, error) {
-	var settings []string
-	var resultCode string
-	var result int
-	var enabledPhp bool
-	var enabledPhpStr string
-	var enabledXML bool
-	var enabledXMLStr string
-	var characterEncoding string
-	var dataPreValidation bool
-	var dataPreValidationStr string
-	var errorMessageLanguage string
-	var useFileMakerPhp bool
-	var useFileMakerPhpStr string
-
-	// get PHP Technology Configuration
-	u, _ := url.Parse(baseURI)
-	u.Path = path.Join(basePath, "php", "config")
-
-	body, exitStatus, err := callURL("GET", u.String(), token, nil)
-	if err != nil {
-		fmt.Println(err.Error())
-		return settings, 10502, err
+// evaluateLintRule returns "PASS", "FAIL" or "SKIP" and the detail of the result
+func evaluateLintRule(rule lintRule, values map[string]string, databases []databaseRecord, maintenance bool) (string, string) {
+	if rule.OutsideMaintenance && maintenance {
+		return "SKIP", "maintenance"
 	}
 
-	//fmt.Println(bytes.NewBuffer([]byte(body)))
-
-	var v interface{}
-	err = json.Unmarshal(body, &v)
-	if err != nil {
-		return settings, 3, err
+	if rule.Setting == "encrypted" {
+		var names []string
+		for _, database := range databases {
+			if !database.IsEncrypted {
+				names = append(names, database.FileName)
+			}
+		}
+		actual := strconv.FormatBool(len(names) == 0)
+		if rule.Equals != nil && !strings.EqualFold(fmt.Sprint(rule.Equals), actual) {
+			if len(names) > 0 {
+				return "FAIL", "not encrypted: " + strings.Join(names, ", ")
+			}
+			return "FAIL", "all databases are encrypted"
+		}
+		return "PASS", actual
 	}
 
-	err = scan.ScanTree(v, "/messages[0]/code", &resultCode)
-	if err != nil {
-		return settings, 3, err
+	value, ok := values[rule.Setting]
+	if !ok {
+		return "FAIL", "not available on the server"
 	}
-	_, _ = strconv.Atoi(resultCode)
-	_ = scan.ScanTree(v, "/response/enabled", &enabledPhp)
-	_ = scan.ScanTree(v, "/response/characterEncoding", &characterEncoding)
-	_ = scan.ScanTree(v, "/response/errorMessageLanguage", &errorMessageLanguage)
-	_ = scan.ScanTree(v, "/response/dataPreValidation", &dataPreValidation)
-	_ = scan.ScanTree(v, "/response/useFileMakerPhp", &useFileMakerPhp)
 
-	enabledPhpStr = "true"
-	if !enabledPhp {
-		enabledPhpStr = "false"
+	if rule.Equals != nil && !strings.EqualFold(fmt.Sprint(rule.Equals), value) {
+		return "FAIL", value + " (expected " + fmt.Sprint(rule.Equals) + ")"
 	}
-
-	if exitStatus == 500 {
-		// for Claris FileMaker Server for Linux
-		dataPreValidationStr = ""
-		useFileMakerPhpStr = "true"
-	} else {
-		dataPreValidationStr = "true"
-		if !dataPreValidation {
-			dataPreValidationStr = "false"
+	if rule.Min != nil || rule.Max != nil {
+		n, err := strconv.Atoi(value)
+		expected := ""
+		if rule.Min != nil && rule.Max != nil {
+			expected = strconv.Itoa(*rule.Min) + "-" + strconv.Itoa(*rule.Max)
+		} else if rule.Min != nil {
+			expected = "at least " + strconv.Itoa(*rule.Min)
+		} else {
+			expected = "at most " + strconv.Itoa(*rule.Max)
+		}
+		if err != nil || (rule.Min != nil && n < *rule.Min) || (rule.Max != nil && n > *rule.Max) {
+			return "FAIL", value + " (expected " + expected + ")"
 		}
+	}
 
-		useFileMakerPhpStr = "true"
-		if !useFileMakerPhp {
-			useFileMakerPhpStr = "false"
+	return "PASS", value
+}
+
+// lintServer evaluates the rules of the policy and returns 1 when a rule fails
+func lintServer(c *cli, baseURI string, token string, policy lintPolicy, maintenance bool) int {
+	values := getServerSettingValues(baseURI, token)
+
+	var databases []databaseRecord
+	for _, rule := range policy.Rules {
+		if rule.Setting == "encrypted" {
+			u, _ := url.Parse(baseURI)
+			u.Path = path.Join(getAPIBasePath(), "databases")
+			var exitStatus int
+			databases, exitStatus = getDatabaseRecords(c.outStream, u.String(), token)
+			if exitStatus != 0 {
+				return exitStatus
+			}
+			break
 		}
 	}
 
-	// get XML Technology Configuration
-	u.Path = path.Join(basePath, "xml", "config")
+	passed, failed, skipped := 0, 0, 0
+	for _, rule := range policy.Rules {
+		state, detail := evaluateLintRule(rule, values, databases, maintenance)
+		switch state {
+		case "PASS":
+			passed++
+		case "FAIL":
+			failed++
+		default:
+			skipped++
+		}
+		label := rule.Setting
+		if len(rule.Name) > 0 {
+			label = rule.Name + " (" + rule.Setting + ")"
+		}
+		fmt.Fprintln(c.outStream, state+"  "+label+": "+detail)
+	}
+	fmt.Fprintln(c.outStream, strconv.Itoa(len(policy.Rules))+" rules, "+strconv.Itoa(passed)+" passed, "+strconv.Itoa(failed)+" failed, "+strconv.Itoa(skipped)+" skipped")
 
-	body, _, err = callURL("GET", u.String(), token, nil)
-	if err != nil {
-		fmt.Println(err.Error())
-		return settings, -1, err
+	if failed > 0 {
+		return 1
 	}
 
-	err = json.Unmarshal(body, &v)
+	return 0
+}
+
+func getBackupTime(c *cli, urlString string, token string, id int, out outputOptions) int {
+	body, _, err := callURL("GET", urlString, token, nil)
 	if err != nil {
 		fmt.Println(err.Error())
+		return -1
 	}
 
-	err = scan.ScanTree(v, "/messages[0]/code", &resultCode)
+	var response schedulesResponse
+	err = decodeResponse(body, &response)
 	if err != nil {
-		return settings, 3, err
-	}
-	result, _ = strconv.Atoi(resultCode)
-	err = scan.ScanTree(v, "/response/enabled", &enabledXML)
-
-	enabledXMLStr = "true"
-	if !enabledXML {
-		enabledXMLStr = "false"
+		fmt.Println(err.Error())
 	}
 
-	settings = append(settings, enabledPhpStr)
-	settings = append(settings, enabledXMLStr)
-	settings = append(settings, characterEncoding)
-	settings = append(settings, errorMessageLanguage)
-	settings = append(settings, dataPreValidationStr)
-	settings = append(settings, useFileMakerPhpStr)
+	var data [][]string
 
-	// output
-	if result == 0 {
-		for _, option := range printOptions {
-			if option == "enablephp" {
-				fmt.Println("EnablePHP = " + enabledPhpStr)
-			}
-			if option == "enablexml" {
-				fmt.Println("EnableXML = " + enabledXMLStr)
+	if len(response.Response.Schedules) > 0 {
+		for _, schedule := range response.Response.Schedules {
+			nextRun := ""
+			if schedule.NextRun != nil {
+				nextRun = *schedule.NextRun
 			}
-			if option == "encoding" {
-				fmt.Println("Encoding = " + characterEncoding + " [ UTF-8 ISO-8859-1 ]")
-			}
-			if option == "locale" {
-				fmt.Println("Locale = " + errorMessageLanguage + " [ en de fr it ja ]")
+			if !schedule.Enabled {
+				nextRun = "Disabled"
 			}
-			if option == "prevalidation" {
-				fmt.Println("PreValidation = " + dataPreValidationStr)
+
+			sID, _ := strconv.Atoi(string(schedule.ID))
+			if id == sID || id == 0 {
+				nextRun = out.formatDateTime(nextRun, "15:04", false)
+				if schedule.taskType() == "Backup" {
+					data = append(data, []string{string(schedule.ID), schedule.Name, nextRun})
+				}
 			}
-			if option == "usefmphp" {
-				fmt.Println("UseFMPHP = " + useFileMakerPhpStr)
+		}
+
+		if len(data) > 0 {
+			table := tablewriter.NewWriter(c.outStream)
+			table.SetAutoWrapText(false)
+			table.SetAutoFormatHeaders(false)
+			for _, v := range data {
+				table.SetHeader([]string{"ID", "Name", "Start time"})
+				table.Append(v)
 			}
+			table.Render()
+		} else {
+			return 10600
 		}
 	}
 
-	return settings, result, err
+	return 0
 }
 
-func getPersistentCacheConfigurations(urlString string, token string, printOptions []string) ([]string, int, error) {
-	var settings []string
-	var resultCode string
-	var result int
-	var persistentCache bool
-	var persistentCacheSync bool
-	var databaseServerAutoRestart bool
+type backupScheduleInfo struct {
+	id     int
+	name   string
+	target string
+}
 
-	persistentCacheStr := "false"
-	persistentCacheSyncStr := "false"
-	databaseServerAutoRestartStr := "false"
+type backupSetInfo struct {
+	schedule  backupScheduleInfo
+	name      string
+	path      string
+	timestamp time.Time
+	size      int64
+	fileCount int
+}
+
+func getBackupSchedules(urlString string, token string, id int) []backupScheduleInfo {
+	var schedules []backupScheduleInfo
 
 	body, _, err := callURL("GET", urlString, token, nil)
 	if err != nil {
-		return settings, 10502, err
+		fmt.Println(err.Error())
+		return schedules
 	}
 
-	var v interface{}
-	err = json.Unmarshal(body, &v)
+	var response schedulesResponse
+	err = decodeResponse(body, &response)
 	if err != nil {
 		fmt.Println(err.Error())
+		return schedules
 	}
 
-	err = scan.ScanTree(v, "/messages[0]/code", &resultCode)
-	if err != nil {
-		return settings, 3, err
+	for _, schedule := range response.Response.Schedules {
+		if schedule.BackupType == nil || schedule.BackupType.BackupTarget == "" {
+			continue
+		}
+		sID, _ := strconv.Atoi(string(schedule.ID))
+		if id == sID || id == 0 {
+			schedules = append(schedules, backupScheduleInfo{id: sID, name: schedule.Name, target: convertServerPathToLocalPath(schedule.BackupType.BackupTarget)})
+		}
 	}
-	result, _ = strconv.Atoi(resultCode)
-	_ = scan.ScanTree(v, "/response/persistentCache", &persistentCache)
-	_ = scan.ScanTree(v, "/response/persistentCacheSync", &persistentCacheSync)
-	_ = scan.ScanTree(v, "/response/databaseServerAutoRestart", &databaseServerAutoRestart)
 
-	if persistentCache {
-		persistentCacheStr = "true"
-	}
-	if persistentCacheSync {
-		persistentCacheSyncStr = "true"
-	}
-	if databaseServerAutoRestart {
-		databaseServerAutoRestartStr = "true"
+	return schedules
+}
+
+// convertServerPathToLocalPath converts a path returned by FileMaker Admin API
+// (e.g. "filelinux:/opt/FileMaker/FileMaker Server/Data/Backups/") to a local path.
+func convertServerPathToLocalPath(p string) string {
+	if strings.HasPrefix(p, "filelinux:") {
+		p = strings.TrimPrefix(p, "filelinux:")
+	} else if strings.HasPrefix(p, "filemac:") {
+		p = "/Volumes" + strings.TrimPrefix(p, "filemac:")
+	} else if strings.HasPrefix(p, "filewin:") {
+		p = strings.TrimPrefix(strings.TrimPrefix(p, "filewin:"), "/")
 	}
 
-	settings = append(settings, persistentCacheStr)
-	settings = append(settings, persistentCacheSyncStr)
-	settings = append(settings, databaseServerAutoRestartStr)
+	return filepath.Clean(filepath.FromSlash(p))
+}
 
-	// output
-	if result == 0 {
-		for _, option := range printOptions {
-			if option == "persistcacheenabled" {
-				fmt.Println("PersistCacheEnabled = " + persistentCacheStr + " [default: false] ")
-			}
-			if option == "syncpersistcache" {
-				fmt.Println("SyncPersistCache = " + persistentCacheSyncStr + " [default: false] ")
-			}
-			if option == "databaseserverautorestart" {
-				fmt.Println("DatabaseServerAutoRestart = " + databaseServerAutoRestartStr + " [default: false] ")
-			}
+func getBackupSets(schedules []backupScheduleInfo) []backupSetInfo {
+	var sets []backupSetInfo
+
+	for _, schedule := range schedules {
+		entries, err := os.ReadDir(schedule.target)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			// FileMaker Server names backup folders "<schedule name>_<yyyy-mm-dd>_<hhmm>"
+			if !entry.IsDir() || !strings.HasPrefix(entry.Name(), schedule.name+"_") {
+				continue
+			}
+			set := backupSetInfo{
+				schedule: schedule,
+				name:     entry.Name(),
+				path:     filepath.Join(schedule.target, entry.Name()),
+			}
+			suffix := strings.TrimPrefix(entry.Name(), schedule.name+"_")
+			if t, err := time.ParseInLocation("2006-01-02_1504", suffix, time.Local); err == nil {
+				set.timestamp = t
+			} else if info, err := entry.Info(); err == nil {
+				set.timestamp = info.ModTime()
+			}
+			_ = filepath.WalkDir(set.path, func(p string, d os.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return nil
+				}
+				if info, err := d.Info(); err == nil {
+					set.size += info.Size()
+				}
+				if strings.EqualFold(filepath.Ext(p), ".fmp12") {
+					set.fileCount++
+				}
+				return nil
+			})
+			sets = append(sets, set)
 		}
 	}
 
-	return settings, result, err
-}
+	sort.SliceStable(sets, func(i, j int) bool {
+		if sets[i].schedule.id != sets[j].schedule.id {
+			return sets[i].schedule.id < sets[j].schedule.id
+		}
+		return sets[i].timestamp.After(sets[j].timestamp)
+	})
 
-func disconnectAllClient(u *url.URL, token string, message string, graceTime int) (int, error) {
-	exitStatus := 0
-	var err error
+	return sets
+}
 
-	// check the client connection
-	u.Path = path.Join(getAPIBasePath(), "clients")
-	idList := getClients(u.String(), token, []string{""})
+func listBackups(c *cli, urlString string, token string, id int) int {
+	schedules := getBackupSchedules(urlString, token, id)
+	if len(schedules) == 0 {
+		return 10600
+	}
 
-	// disconnect clients
-	if len(idList) > 0 {
-		for i := 0; i < len(idList); i++ {
-			u.Path = path.Join(getAPIBasePath(), "clients", strconv.Itoa(idList[i]))
-			u.RawQuery = "messageText=" + url.QueryEscape(message) + "&graceTime=" + url.QueryEscape(strconv.Itoa(graceTime))
-			exitStatus, _, err = sendRequest("DELETE", u.String(), token, params{command: "disconnect"})
-			if err != nil {
-				break
-			}
+	sets := getBackupSets(schedules)
+	if len(sets) > 0 {
+		table := tablewriter.NewWriter(c.outStream)
+		table.SetAutoWrapText(false)
+		table.SetAutoFormatHeaders(false)
+		table.SetHeader([]string{"ID", "Schedule", "Backup", "Time", "Size", "Files"})
+		for _, set := range sets {
+			table.Append([]string{strconv.Itoa(set.schedule.id), set.schedule.name, set.name, set.timestamp.Format("2006/01/02 15:04"), strconv.FormatInt(set.size, 10), strconv.Itoa(set.fileCount)})
 		}
+		table.Render()
 	}
 
-	return exitStatus, err
+	return 0
 }
 
-func stopDatabaseServer(u *url.URL, token string, message string, graceTime int) (int, error) {
-	exitStatus := -1
-	forceFlag := false
-	var err error
-
-	// disconnect clients
-	_, _ = disconnectAllClient(u, token, message, graceTime)
-
-	// close databases
-	u.Path = path.Join(getAPIBasePath(), "databases")
-	idList, _, _ := getDatabases(u.String(), token, []string{""}, "NORMAL", false)
-	if len(idList) > 0 {
-		for i := 0; i < len(idList); i++ {
-			u.Path = path.Join(getAPIBasePath(), "databases", strconv.Itoa(idList[i]))
-			if graceTime == 0 {
-				forceFlag = true
-			}
-			_, _, _ = sendRequest("PATCH", u.String(), token, params{command: "close", messageText: message, force: forceFlag})
-		}
+func findBackupSet(urlString string, token string, backup string) (string, bool) {
+	if info, err := os.Stat(backup); err == nil && info.IsDir() {
+		return backup, true
 	}
 
-	var openedID []int
-	for value := 0; ; {
-		time.Sleep(1 * time.Second)
-		value++
-		u.Path = path.Join(getAPIBasePath(), "databases")
-		openedID, _, _ = getDatabases(u.String(), token, []string{""}, "CLOSING", false)
-		if len(openedID) == 0 || value > 120 {
-			break
+	for _, set := range getBackupSets(getBackupSchedules(urlString, token, 0)) {
+		if set.name == backup {
+			return set.path, true
 		}
 	}
 
-	// stop database server
-	u.Path = path.Join(getAPIBasePath(), "server", "status")
-	exitStatus, _, err = sendRequest("PATCH", u.String(), token, params{status: "STOPPED"})
-
-	return exitStatus, err
+	return "", false
 }
 
-func waitStoppingServer(u *url.URL, token string) (int, error) {
-	exitStatus := 0
-	var err error
-	var running string
-
-	for value := 0; ; {
-		time.Sleep(1 * time.Second)
-		value++
-		u.Path = path.Join(getAPIBasePath(), "server", "status")
-		exitStatus, running, err = sendRequest("GET", u.String(), token, params{})
-		if running == "STOPPED" || value > 120 {
-			break
+func findBackupFile(dir string, name string) string {
+	found := ""
+	_ = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || found != "" {
+			return nil
 		}
-	}
+		if comparePath(d.Name(), name) {
+			found = p
+		}
+		return nil
+	})
 
-	return exitStatus, err
+	return found
 }
 
-func getBackupTime(urlString string, token string, id int) int {
-	body, _, err := callURL("GET", urlString, token, nil)
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
 	if err != nil {
-		fmt.Println(err.Error())
-		return -1
+		return err
 	}
+	defer in.Close()
 
-	var v interface{}
-	body2 := []byte(body)
-	err = json.Unmarshal(body2, &v)
+	info, err := in.Stat()
 	if err != nil {
-		fmt.Println(err.Error())
+		return err
 	}
 
-	var count int
-	var c []string
-	var s1 string
-	var sID int
-	var name string
-	var taskType string
-	var backupType string
-	var filemakerScriptType string
-	var messageType string
-	var scriptSequenceType string
-	var systemScriptType string
-	var verifyType string
-	var nextRun string
-	var enabled bool
-	var status string
-	var data [][]string
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
 
-	_ = scan.ScanTree(v, "/response/schedules", &c)
-	count = len(c)
+	return out.Close()
+}
 
-	if count > 0 {
-		for i := 0; i < count; i++ {
-			_ = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/id", &s1)
-			_ = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/name", &name)
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/backupType/resourceType", &backupType)
-			if err != nil {
-				backupType = ""
-			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/filemakerScriptType/resource", &filemakerScriptType)
-			if err != nil {
-				filemakerScriptType = ""
-			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/messageType/resourceType", &messageType)
-			if err != nil {
-				messageType = ""
-			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/scriptSequenceType/resource", &scriptSequenceType)
-			if err != nil {
-				scriptSequenceType = ""
-			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/systemScriptType/osScript", &systemScriptType)
-			if err != nil {
-				systemScriptType = ""
+func restoreDatabases(c *cli, u *url.URL, token string, backup string, files []string, dryRun bool, yesFlag bool, message string, forceFlag bool, key string, saveKeyFlag bool) int {
+	u.Path = path.Join(getAPIBasePath(), "schedules")
+	dir, ok := findBackupSet(u.String(), token, backup)
+	if !ok {
+		fmt.Fprintln(c.outStream, "fmcsadmin: Backup not found: "+backup)
+		return 20405
+	}
+
+	u.Path = path.Join(getAPIBasePath(), "databases")
+	idList, pathList, _ := getDatabases(u.String(), token, files, "", true)
+	if len(idList) == 0 {
+		return 10904
+	}
+
+	var sources []string
+	var destinations []string
+	for i := 0; i < len(idList); i++ {
+		source := findBackupFile(dir, filepath.Base(convertServerPathToLocalPath(pathList[i])))
+		if source == "" {
+			fmt.Fprintln(c.outStream, "fmcsadmin: "+path.Base(pathList[i])+" is not included in "+backup)
+			return 20405
+		}
+		sources = append(sources, source)
+		destinations = append(destinations, convertServerPathToLocalPath(pathList[i]))
+	}
+
+	for i := 0; i < len(idList); i++ {
+		fmt.Fprintln(c.outStream, "Close: "+pathList[i])
+		fmt.Fprintln(c.outStream, "Remove: "+pathList[i])
+		fmt.Fprintln(c.outStream, "Copy: "+sources[i]+" -> "+destinations[i])
+		fmt.Fprintln(c.outStream, "Open: "+pathList[i])
+	}
+	if dryRun {
+		return 0
+	}
+
+	res := ""
+	if yesFlag && forceFlag {
+		// replacing databases is confirmed even with -y unless --force is also specified
+		res = "y"
+	} else {
+		fmt.Fprint(c.outStream, "fmcsadmin: really restore database(s)? The current file(s) will be replaced. (y, n) ")
+		input, _ := c.input().ReadString('\n')
+		res = strings.ToLower(strings.TrimSpace(input))
+	}
+	if res != "y" {
+		return 0
+	}
+
+	for i := 0; i < len(idList); i++ {
+		id := strconv.Itoa(idList[i])
+		name := path.Base(pathList[i])
+
+		u.Path = path.Join(getAPIBasePath(), "databases")
+		if openedID, _, _ := getDatabases(u.String(), token, []string{id}, "CLOSED", false); len(openedID) == 0 {
+			fmt.Fprintln(c.outStream, "File Closing: "+name)
+			u.Path = path.Join(getAPIBasePath(), "databases", id)
+			exitStatus, _, err := sendRequest("PATCH", u.String(), token, params{command: "close", messageText: message, force: forceFlag})
+			if exitStatus != 0 || err != nil {
+				return exitStatus
+			}
+			var closedID []int
+			for value := 0; ; {
+				value++
+				u.Path = path.Join(getAPIBasePath(), "databases")
+				closedID, _, _ = getDatabases(u.String(), token, []string{id}, "CLOSED", false)
+				if len(closedID) > 0 || value > 30 {
+					break
+				}
+				timeSleep(1 * time.Second)
 			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/verifyType/resourceType", &verifyType)
-			if err != nil {
-				verifyType = ""
+			if len(closedID) == 0 {
+				return c.timedOut("closing " + name)
 			}
-			err = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/nextRun", &nextRun)
-			if err != nil {
-				nextRun = ""
+			fmt.Fprintln(c.outStream, "File Closed: "+name)
+		}
+
+		u.Path = path.Join(getAPIBasePath(), "databases", id)
+		exitStatus, _, err := sendRequest("DELETE", u.String(), token, params{})
+		if exitStatus != 0 || err != nil {
+			return exitStatus
+		}
+		for value := 0; ; {
+			value++
+			if _, err := os.Stat(destinations[i]); os.IsNotExist(err) {
+				break
 			}
-			_ = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/enabled", &enabled)
-			if !enabled {
-				nextRun = "Disabled"
+			if value > 30 {
+				return c.timedOut("removing " + name)
 			}
-			_ = scan.ScanTree(v, "/response/schedules["+strconv.Itoa(i)+"]/status", &status)
+			timeSleep(1 * time.Second)
+		}
+		fmt.Fprintln(c.outStream, "File Removed: "+pathList[i])
 
-			sID, _ = strconv.Atoi(s1)
-			if id == sID || id == 0 {
-				if backupType != "" {
-					taskType = "Backup"
-				} else if filemakerScriptType != "" {
-					taskType = "FileMaker Script"
-				} else if messageType != "" {
-					taskType = "Message"
-				} else if scriptSequenceType != "" {
-					taskType = "Script Sequence"
-				} else if systemScriptType != "" {
-					taskType = "System Script"
-				} else if verifyType != "" {
-					taskType = "Verify"
-				}
-				nextRun = getDateTimeStringOfCurrentTimeZone(nextRun, "15:04", false)
-				if taskType == "Backup" {
-					data = append(data, []string{s1, name, nextRun})
-				}
+		if err := copyFile(sources[i], destinations[i]); err != nil {
+			fmt.Fprintln(c.outStream, "fmcsadmin: "+err.Error())
+			if os.IsExist(err) {
+				return 20406
 			}
+			return 20402
 		}
+		fmt.Fprintln(c.outStream, "File Restored: "+destinations[i])
 
-		if len(data) > 0 {
-			table := tablewriter.NewWriter(os.Stdout)
-			table.SetAutoWrapText(false)
-			table.SetAutoFormatHeaders(false)
-			for _, v := range data {
-				table.SetHeader([]string{"ID", "Name", "Start time"})
-				table.Append(v)
-			}
-			table.Render()
-		} else {
-			return 10600
+		exitStatus = openDatabases(c, u, token, []string{pathList[i]}, key, saveKeyFlag, false)
+		if exitStatus != 0 {
+			return exitStatus
+		}
+		// an encrypted database stays closed without an error when the password is wrong
+		u.Path = path.Join(getAPIBasePath(), "databases")
+		if openedID, _, _ := getDatabases(u.String(), token, []string{pathList[i]}, "NORMAL", false); len(openedID) == 0 {
+			return 802
 		}
 	}
 
@@ -4728,13 +8863,75 @@ func comparePath(name1 string, name2 string) bool {
 	return false
 }
 
+// apiError is an error response of the Admin API.
+type apiError struct {
+	statusCode int
+	code       int
+	message    string
+	endpoint   string
+}
+
+func (e *apiError) Error() string {
+	s := e.endpoint + ": HTTP " + strconv.Itoa(e.statusCode)
+	if e.code != 0 {
+		s = s + ", error " + strconv.Itoa(e.code)
+	}
+	if e.message != "" {
+		s = s + ": " + e.message
+	}
+
+	return s
+}
+
+// lastAPIError is the last error response of the Admin API in the command,
+// used to print the message of the server with the error code.
+var lastAPIError *apiError
+
+// newAPIError returns the error of the response, or nil when the request succeeded.
+func newAPIError(method string, urlPath string, statusCode int, body []byte) *apiError {
+	e := &apiError{statusCode: statusCode, endpoint: method + " " + redactPath(urlPath)}
+	var response output
+	if json.Unmarshal(body, &response) == nil {
+		for _, message := range response.Messages {
+			code, err := strconv.Atoi(message.Code)
+			if err == nil && code != 0 {
+				e.code = code
+				e.message = strings.TrimSpace(message.Text)
+				break
+			}
+		}
+	} else if statusCode >= 400 {
+		e.message = http.StatusText(statusCode)
+	}
+	if e.code == 0 && statusCode < 400 {
+		return nil
+	}
+
+	return e
+}
+
 func outputErrorMessage(code int, c *cli) {
 	if code >= -1 {
 		if code == 1701 {
 			// when fmserverd is stopping
 			code = 10502
 		}
-		fmt.Fprintln(c.outStream, "Error: "+strconv.Itoa(code)+" ("+getErrorDescription(code)+")")
+		description := getErrorDescription(code)
+		if lastAPIError != nil && lastAPIError.code == code && lastAPIError.message != "" && !strings.EqualFold(lastAPIError.message, description) {
+			if description == "" {
+				description = lastAPIError.message
+			} else {
+				description = description + ": " + lastAPIError.message
+			}
+		}
+		if description == "" {
+			description = "Unknown error"
+		}
+		fmt.Fprintln(c.outStream, "Error: "+strconv.Itoa(code)+" ("+description+")")
+		if lastAPIError != nil && (apiLogger != nil || code == 3 || code == 10001) {
+			// print the endpoint and the status code for generic errors and with --debug
+			fmt.Fprintln(c.outStream, "       "+lastAPIError.Error())
+		}
 	}
 }
 
@@ -4879,6 +9076,15 @@ func sendRequest(method string, urlString string, token string, p params) (int,
 				requiresecuredb,
 			}
 			jsonStr, _ = json.Marshal(d)
+		} else if strings.HasSuffix(urlString, "/fmdapi/config") || strings.HasSuffix(urlString, "/odata/config") || strings.HasSuffix(urlString, "/webdirect/config") {
+			enabled := true
+			if p.enabled == "false" {
+				enabled = false
+			}
+			d := connectorConfigInfo{
+				enabled,
+			}
+			jsonStr, _ = json.Marshal(d)
 		} else if strings.HasSuffix(urlString, "/php/config") {
 			enabled := true
 			if p.enabled == "false" {
@@ -4925,44 +9131,211 @@ func sendRequest(method string, urlString string, token string, p params) (int,
 		jsonStr = []byte("")
 	}
 
-	body, statusCode, err := callURL(method, urlString, token, bytes.NewBuffer([]byte(jsonStr)))
+	body, statusCode, err := callURL(method, urlString, token, bytes.NewBuffer([]byte(jsonStr)))
+	if err != nil {
+		return -1, "", err
+	}
+
+	if statusCode >= 400 {
+		u, _ := url.Parse(urlString)
+		if e := newAPIError(method, u.Path, statusCode, body); e != nil && e.code != 0 {
+			return e.code, "", err
+		}
+		return 10001, "", err
+	}
+
+	if body != nil {
+		output := output{}
+		if json.Unmarshal(body, &output) == nil {
+			for i := 0; i < len(output.Messages); i++ {
+				if reflect.ValueOf(output.Messages[i].Code).IsValid() {
+					code, _ = strconv.Atoi(output.Messages[i].Code)
+					break
+				}
+			}
+			return code, output.Response.Status, nil
+		} else {
+			// In case of detecting a server-side error
+			return 3, "", err
+		}
+	}
+
+	return 0, "", nil
+}
+
+// apiLogger logs the Admin API traffic when --debug, --trace or FMCSADMIN_LOG is specified.
+var apiLogger *trafficLogger
+
+// trafficLogger writes a line for each Admin API request, and the redacted
+// headers and bodies of the request and the response at the trace level.
+type trafficLogger struct {
+	out   io.Writer
+	trace bool
+}
+
+var redactedValuePattern = regexp.MustCompile(`(?i)("\w*(?:password|token|key|secret)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+var privateKeyPattern = regexp.MustCompile(`-----BEGIN ([A-Z ]*)PRIVATE KEY-----(?:.|\n|\\n)*?-----END ([A-Z ]*)PRIVATE KEY-----`)
+
+// parseLogSetting parses the value of FMCSADMIN_LOG ("LEVEL" or "LEVEL:FILE").
+func parseLogSetting(value string) (string, string, error) {
+	if value == "" {
+		return "", "", nil
+	}
+	level, file, _ := strings.Cut(value, ":")
+	level = strings.ToLower(level)
+	if level != "debug" && level != "trace" {
+		return "", "", fmt.Errorf("invalid log level: %q", level)
+	}
+
+	return level, file, nil
+}
+
+// redactBody masks passwords, tokens, encryption keys and private keys in a request or response body.
+func redactBody(body []byte) string {
+	s := redactedValuePattern.ReplaceAllString(string(body), `$1"****"`)
+	s = privateKeyPattern.ReplaceAllString(s, "-----BEGIN ${1}PRIVATE KEY-----****-----END ${2}PRIVATE KEY-----")
+
+	return strings.TrimSpace(s)
+}
+
+// redactAuthorization keeps only the scheme of an Authorization header (e.g. "Bearer ****").
+func redactAuthorization(value string) string {
+	if value == "" {
+		return ""
+	}
+	scheme, _, found := strings.Cut(value, " ")
+	if !found {
+		return "****"
+	}
+
+	return scheme + " ****"
+}
+
+// redactPath masks the access token in the path for logging out (e.g. "/fmi/admin/api/v2/user/auth/****").
+func redactPath(urlPath string) string {
+	prefix := path.Join(getAPIBasePath(), "user", "auth") + "/"
+	if strings.HasPrefix(urlPath, prefix) {
+		return prefix + "****"
+	}
+
+	return urlPath
+}
+
+func (l *trafficLogger) logRetry(req *http.Request, wait time.Duration, attempt int, retries int) {
+	if l == nil {
+		return
+	}
+
+	fmt.Fprintf(l.out, "[fmcsadmin] retrying %s %s in %s (%d/%d)\n", req.Method, redactPath(req.URL.Path), wait.Round(time.Millisecond), attempt, retries)
+}
+
+func (l *trafficLogger) logRequest(req *http.Request, requestBody []byte, statusCode int, latency time.Duration, responseBody []byte, err error) {
+	if l == nil {
+		return
+	}
+
+	status := strconv.Itoa(statusCode)
 	if err != nil {
-		return -1, "", err
+		status = "error: " + err.Error()
+	}
+	fmt.Fprintf(l.out, "[fmcsadmin] %s %s %s %dms\n", req.Method, redactPath(req.URL.Path), status, latency.Milliseconds())
+	if !l.trace {
+		return
 	}
 
-	// for debugging
-	/*
-		fmt.Println(method)
-		fmt.Println(urlString)
-		fmt.Println(statusCode)
-		fmt.Println(string(jsonStr))
-		fmt.Println(bytes.NewBuffer([]byte(body)))
-	*/
+	if authorization := req.Header.Get("Authorization"); authorization != "" {
+		fmt.Fprintln(l.out, "[fmcsadmin] > Authorization: "+redactAuthorization(authorization))
+	}
+	if len(requestBody) > 0 {
+		fmt.Fprintln(l.out, "[fmcsadmin] > "+redactBody(requestBody))
+	}
+	if len(responseBody) > 0 {
+		fmt.Fprintln(l.out, "[fmcsadmin] < "+redactBody(responseBody))
+	}
+}
 
-	if statusCode >= 400 {
-		return 10001, "", err
+// apiRetryPolicy is the policy for retrying Admin API requests specified by --retries.
+var apiRetryPolicy = retryPolicy{}
+
+// retryPolicy retries requests failing with a transient error with exponential backoff and jitter.
+type retryPolicy struct {
+	retries int
+	maxWait time.Duration
+	// nonIdempotent allows to retry requests other than GET requests and logins
+	nonIdempotent bool
+}
+
+const defaultRetryMaxWait = 30 * time.Second
+const retryBaseWait = 500 * time.Millisecond
+
+// parseRetryMaxWait parses the value of --retry-max-wait (e.g. "30s" or "30").
+func parseRetryMaxWait(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultRetryMaxWait, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration: %q", value)
 	}
 
-	if body != nil {
-		output := output{}
-		if json.Unmarshal(body, &output) == nil {
-			for i := 0; i < len(output.Messages); i++ {
-				if reflect.ValueOf(output.Messages[i].Code).IsValid() {
-					code, _ = strconv.Atoi(output.Messages[i].Code)
-					break
-				}
+	return d, nil
+}
+
+// isRetryable reports whether the failed request may be sent again. Connection
+// errors, 5xx responses and the result codes 956 (maximum number of sessions)
+// and 1701 (the server is stopping) are transient.
+func (p retryPolicy) isRetryable(method string, urlPath string, statusCode int, body []byte, err error) bool {
+	if method != "GET" && method != "HEAD" && urlPath != path.Join(getAPIBasePath(), "user", "auth") && !p.nonIdempotent {
+		return false
+	}
+	if err != nil || statusCode >= 500 {
+		return true
+	}
+
+	var response output
+	if json.Unmarshal(body, &response) == nil {
+		for _, message := range response.Messages {
+			if message.Code == "956" || message.Code == "1701" {
+				return true
 			}
-			return code, output.Response.Status, nil
-		} else {
-			// In case of detecting a server-side error
-			return 3, "", err
 		}
 	}
 
-	return 0, "", nil
+	return false
+}
+
+// getWait returns the wait before the retry of the attempt (starting from 1).
+func (p retryPolicy) getWait(attempt int) time.Duration {
+	maxWait := p.maxWait
+	if maxWait <= 0 {
+		maxWait = defaultRetryMaxWait
+	}
+	wait := maxWait
+	if attempt < 16 && retryBaseWait<<(attempt-1) < maxWait {
+		wait = retryBaseWait << (attempt - 1)
+	}
+
+	// jitter in the upper half of the wait
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+
+	return wait/2 + time.Duration(binary.BigEndian.Uint64(b[:])%uint64(wait/2+1))
 }
 
 func callURL(method string, urlString string, token string, request io.Reader) ([]byte, int, error) {
+	return callURLWithOptions(method, urlString, token, request, "application/json", time.Duration(5)*time.Second)
+}
+
+func callURLWithOptions(method string, urlString string, token string, request io.Reader, contentType string, timeout time.Duration) ([]byte, int, error) {
+	var requestBody []byte
+	if apiLogger != nil && apiLogger.trace && request != nil && contentType == "application/json" {
+		requestBody, _ = io.ReadAll(request)
+		request = bytes.NewReader(requestBody)
+	}
+
 	req, err := http.NewRequest(method, urlString, request)
 	if err != nil {
 		fmt.Println(err.Error())
@@ -4972,36 +9345,59 @@ func callURL(method string, urlString string, token string, request io.Reader) (
 	if request == nil {
 		req.Header.Set("Content-Length", "0")
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if len(token) >= 5 && (token[:5] == "FMID " || token[:6] == "Basic " || token[:4] == "PKI ") {
-		req.Header.Set("Authorization", token)
-	} else {
-		req.Header.Set("Authorization", "Bearer "+strings.Replace(strings.Replace(token, "\n", "", -1), "\r", "", -1))
-	}
-	client := &http.Client{Timeout: time.Duration(5) * time.Second}
-	res, err := client.Do(req)
-	if err != nil {
-		// for debugging
-		//fmt.Println(err.Error())
+	req.Header.Set("Content-Type", contentType)
+	setAuthorizationHeader(req, token)
+	client := &http.Client{Timeout: timeout}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			req.Body, _ = req.GetBody()
+		}
 
-		if res == nil {
-			return []byte(""), 404, err
+		start := time.Now()
+		res, err := client.Do(req)
+		statusCode := 0
+		var body []byte
+		var readErr error
+		if err == nil {
+			statusCode = res.StatusCode
+			body, readErr = io.ReadAll(res.Body)
+			res.Body.Close()
 		}
+		apiLogger.logRequest(req, requestBody, statusCode, time.Since(start), body, errors.Join(err, readErr))
 
-		return []byte(""), res.StatusCode, err
-	}
-	defer res.Body.Close()
-	body, err := io.ReadAll(res.Body)
+		if attempt < apiRetryPolicy.retries && (request == nil || req.GetBody != nil) && apiRetryPolicy.isRetryable(method, req.URL.Path, statusCode, body, errors.Join(err, readErr)) {
+			wait := apiRetryPolicy.getWait(attempt + 1)
+			apiLogger.logRetry(req, wait, attempt+1, apiRetryPolicy.retries)
+			timeSleep(wait)
+			continue
+		}
 
-	// for debugging
-	//fmt.Println(bytes.NewBuffer([]byte(body)))
+		if e := newAPIError(method, req.URL.Path, statusCode, body); err == nil && e != nil {
+			lastAPIError = e
+		}
 
-	if err != nil {
-		fmt.Println(err.Error())
-		return []byte(""), res.StatusCode, err
+		if err != nil {
+			if res == nil {
+				return []byte(""), 404, err
+			}
+
+			return []byte(""), res.StatusCode, err
+		}
+		if readErr != nil {
+			fmt.Println(readErr.Error())
+			return []byte(""), res.StatusCode, readErr
+		}
+
+		return body, res.StatusCode, nil
 	}
+}
 
-	return body, res.StatusCode, err
+func setAuthorizationHeader(req *http.Request, token string) {
+	if len(token) >= 5 && (token[:5] == "FMID " || token[:6] == "Basic " || token[:4] == "PKI ") {
+		req.Header.Set("Authorization", token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+strings.Replace(strings.Replace(token, "\n", "", -1), "\r", "", -1))
+	}
 }
 
 func detectHostUnreachable(exitStatus int) bool {
@@ -5023,101 +9419,289 @@ func detectHostUnreachable(exitStatus int) bool {
 	}
 }
 
+// errorDescriptions is the table of the FileMaker, Admin API and fmcsadmin error codes.
+var errorDescriptions = map[int]string{
+	-1:    "Unknown error",
+	3:     "Unavailable command",
+	4:     "Command is unknown",
+	8:     "Empty result",
+	9:     "Access denied",
+	10:    "Requested data is missing",
+	11:    "Name is not valid",
+	12:    "Name already exists",
+	21:    "Not Supported",
+	100:   "File is missing",
+	212:   "Invalid user account and/or password; please try again",
+	214:   "Too many login attempts, account locked out",
+	802:   "Unable to open the file",
+	812:   "Exceeded host's capacity",
+	956:   "Maximum number of Admin API sessions exceeded",
+	958:   "Parameter missing",
+	960:   "Parameter is invalid",
+	1700:  "Resource doesn't exist",
+	1701:  "Database Server is not running or is stopping",
+	1702:  "Authentication information wasn't provided in the correct format; verify the value of the Authorization header",
+	1704:  "Resource doesn't support the specified HTTP verb",
+	1705:  "Required HTTP header wasn't specified",
+	1706:  "Parameter isn't supported",
+	1707:  "Required parameter wasn't specified in request",
+	1708:  "Parameter value is invalid",
+	1709:  "Operation is invalid for resource's current state",
+	1710:  "JSON input isn't syntactically valid",
+	1711:  "Host's license has expired",
+	1712:  "Private key file already exists; remove it and run the command again",
+	1713:  "The API request is not supported for this operating system",
+	1717:  "PHP config file does not exist; PHP may not be installed on the server",
+	10001: "Invalid parameter",
+	// When a script runs and a service is already executing (for example, during a long loop), the FileMaker error 10006, "kServiceAlreadyRunning," is returned.
+	10006: "Service already running",
+	10007: "Requested object does not exist",
+	10502: "Host unreachable",
+	10600: "Schedule at specified index does not exist",
+	10601: "Schedule is misconfigured; invalid taskType or run status",
+	10603: "Schedule can't be created or duplicated",
+	10604: "Cannot enable schedule",
+	10610: "No schedules created in configuration file",
+	10611: "Schedule name is already used",
+	10904: "No applicable files for this operation",
+	10906: "Script is missing",
+	// When a script schedule stops executing, the FileMaker error code 10908, "System script aborted," is returned.
+	10908: "System script aborted",
+	11000: "Invalid command",
+	11001: "Invalid option",
+	11002: "Unable to create command",
+	11005: "Disconnect Client invalid ID",
+	20402: "File permission error",
+	20405: "File not found or not accessible.",
+	20406: "File already exists",
+	20408: "File read error",
+	20501: "Directory not empty",
+	20630: "SSL certificate expired",
+	20632: "SSL certificate verification error",
+	25004: "Parameters are invalid",
+	25006: "Invalid session error",
+}
+
 func getErrorDescription(errorCode int) string {
-	description := ""
-
-	switch errorCode {
-	case -1:
-		description = "Unknown error"
-	case 3:
-		description = "Unavailable command"
-	case 4:
-		description = "Command is unknown"
-	case 8:
-		description = "Empty result"
-	case 9:
-		description = "Access denied"
-	case 21:
-		description = "Not Supported"
-	case 212:
-		description = "Invalid user account and/or password; please try again"
-	case 214:
-		description = "Too many login attempts, account locked out"
-	case 802:
-		description = "Unable to open the file"
-	case 956:
-		description = "Maximum number of Admin API sessions exceeded"
-	case 958:
-		description = "Parameter missing"
-	case 960:
-		description = "Parameter is invalid"
-	case 1700:
-		description = "Resource doesn't exist"
-	case 1702:
-		description = "Authentication information wasn't provided in the correct format; verify the value of the Authorization header"
-	case 1708:
-		description = "Parameter value is invalid"
-	case 1713:
-		description = "The API request is not supported for this operating system"
-	case 1717:
-		description = "PHP config file does not exist; PHP may not be installed on the server"
-	case 10001:
-		description = "Invalid parameter"
-	case 10006:
-		// When a script runs and a service is already executing (for example, during a long loop), the FileMaker error 10006, "kServiceAlreadyRunning," is returned.
-		description = "Service already running"
-	case 10007:
-		description = "Requested object does not exist"
-	case 10502:
-		description = "Host unreachable"
-	case 10600:
-		description = "Schedule at specified index does not exist"
-	case 10601:
-		description = "Schedule is misconfigured; invalid taskType or run status"
-	case 10603:
-		description = "Schedule can't be created or duplicated"
-	case 10604:
-		description = "Cannot enable schedule"
-	case 10610:
-		description = "No schedules created in configuration file"
-	case 10611:
-		description = "Schedule name is already used"
-	case 10904:
-		description = "No applicable files for this operation"
-	case 10906:
-		description = "Script is missing"
-	case 10908:
-		// When a script schedule stops executing, the FileMaker error code 10908, "System script aborted," is returned.
-		description = "System script aborted"
-	case 11000:
-		description = "Invalid command"
-	case 11002:
-		description = "Unable to create command"
-	case 11005:
-		description = "Disconnect Client invalid ID"
-	case 20402:
-		description = "File permission error"
-	case 20405:
-		description = "File not found or not accessible."
-	case 20406:
-		description = "File already exists"
-	case 20408:
-		description = "File read error"
-	case 20501:
-		description = "Directory not empty"
-	case 20630:
-		description = "SSL certificate expired"
-	case 20632:
-		description = "SSL certificate verification error"
-	case 25004:
-		description = "Parameters are invalid"
-	case 25006:
-		description = "Invalid session error"
+	return errorDescriptions[errorCode]
+}
+
+// showErrorCodes prints the description of the error code, or all the error codes when no code is specified.
+func showErrorCodes(c *cli, args []string) int {
+	if len(args) == 0 {
+		var codes []int
+		for code := range errorDescriptions {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(c.outStream, "%6d  %s\n", code, errorDescriptions[code])
+		}
+		return 0
+	}
+
+	exitStatus := 0
+	for _, arg := range args {
+		code, err := strconv.Atoi(arg)
+		if err != nil {
+			fmt.Fprintln(c.outStream, "fmcsadmin: invalid error code: "+arg)
+			exitStatus = 10001
+			continue
+		}
+		description := getErrorDescription(code)
+		if description == "" {
+			fmt.Fprintln(c.outStream, "fmcsadmin: unknown error code: "+arg)
+			exitStatus = 10001
+			continue
+		}
+		fmt.Fprintln(c.outStream, "Error: "+strconv.Itoa(code)+" ("+description+")")
+	}
+
+	return exitStatus
+}
+
+func isTerminalWriter(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// isOptionDisabled reports whether a boolean option is turned off explicitly (e.g. "--human=false").
+func isOptionDisabled(args []string, option string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(strings.ToLower(arg), option+"=") {
+			if b, err := strconv.ParseBool(arg[len(option)+1:]); err == nil && !b {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func formatSize(size int64) string {
+	units := []string{"KB", "MB", "GB", "TB"}
+	if size < 1024 {
+		return strconv.FormatInt(size, 10) + " B"
+	}
+
+	value := float64(size)
+	unit := ""
+	for _, unit = range units {
+		value /= 1024
+		if value < 1024 {
+			break
+		}
+	}
+
+	return strconv.FormatFloat(value, 'f', 1, 64) + " " + unit
+}
+
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	d = d.Round(time.Second)
+
+	days := int(d / (24 * time.Hour))
+	hours := int(d / time.Hour % 24)
+	minutes := int(d / time.Minute % 60)
+	seconds := int(d / time.Second % 60)
+
+	switch {
+	case days > 0:
+		if hours > 0 {
+			return strconv.Itoa(days) + "d" + strconv.Itoa(hours) + "h"
+		}
+		return strconv.Itoa(days) + "d"
+	case hours > 0:
+		if minutes > 0 {
+			return strconv.Itoa(hours) + "h" + strconv.Itoa(minutes) + "m"
+		}
+		return strconv.Itoa(hours) + "h"
+	case minutes > 0:
+		return strconv.Itoa(minutes) + "m"
+	}
+
+	return strconv.Itoa(seconds) + "s"
+}
+
+// getRelativeTimeString converts a time formatted as "2006/01/02 15:04" to a relative time such as "in 2h" or "5m ago".
+func getRelativeTimeString(dateTime string, now time.Time) string {
+	t, err := time.ParseInLocation("2006/01/02 15:04", dateTime, now.Location())
+	if err != nil {
+		return dateTime
+	}
+
+	d := t.Sub(now)
+	if d > -time.Minute && d < time.Minute {
+		return "now"
+	} else if d > 0 {
+		return "in " + formatDuration(d)
+	}
+
+	return formatDuration(d) + " ago"
+}
+
+type outputOptions struct {
+	human    bool
+	timeZone string
+	// location is the time zone to print timestamps in (nil keeps the legacy behavior)
+	location *time.Location
+	// serverLocation is the time zone of the server used for timestamps without an offset
+	// (nil when the time zone of the server is unknown)
+	serverLocation *time.Location
+}
+
+func isValidTimeZone(name string) bool {
+	switch strings.ToLower(name) {
+	case "local", "utc", "server":
+		return true
+	}
+	_, err := time.LoadLocation(name)
+
+	return err == nil
+}
+
+// resolve determines the time zones specified by the --tz option.
+func (o outputOptions) resolve(baseURI string, usingCloud bool) outputOptions {
+	if len(o.timeZone) == 0 {
+		return o
+	}
+
+	o.serverLocation = getServerLocation(baseURI, usingCloud)
+
+	switch strings.ToLower(o.timeZone) {
+	case "local":
+		o.location = time.Local
+	case "utc":
+		o.location = time.UTC
+	case "server":
+		o.location = o.serverLocation
 	default:
-		description = ""
+		o.location, _ = time.LoadLocation(o.timeZone)
+	}
+
+	return o
+}
+
+func (o outputOptions) now() time.Time {
+	if o.location != nil {
+		return timeNow().In(o.location)
+	}
+
+	return timeNow()
+}
+
+// formatDateTime converts a timestamp returned by FileMaker Admin API to the time zone
+// specified by the --tz option, keeping the layout of the table. Timestamps are not
+// converted when the time zone of the server they are returned in is unknown.
+func (o outputOptions) formatDateTime(dateTime string, layout string, usingCloud bool) string {
+	if o.location == nil {
+		return getDateTimeStringOfCurrentTimeZone(dateTime, layout, usingCloud)
+	}
+
+	t, ok := parseServerDateTime(dateTime, o.serverLocation, usingCloud)
+	if !ok {
+		return getDateTimeStringOfCurrentTimeZone(dateTime, layout, usingCloud)
+	}
+
+	return t.In(o.location).Format(layout)
+}
+
+func parseServerDateTime(dateTime string, serverLocation *time.Location, usingCloud bool) (time.Time, bool) {
+	if usingCloud {
+		serverLocation = time.UTC
+	}
+
+	if t, err := time.Parse("2006-01-02T15:04:05.000Z", dateTime); err == nil {
+		// for clients (FileMaker Server)
+		return t, true
+	} else if serverLocation == nil {
+		return time.Time{}, false
+	} else if t, err := time.ParseInLocation("2006-01-02 15:04:05 MST", dateTime, serverLocation); err == nil {
+		return t, true
+	} else if t, err := time.ParseInLocation("2006-01-02T15:04:05", dateTime, serverLocation); err == nil {
+		// for schedules
+		return t, true
+	} else if t, err := time.ParseInLocation("01/02/2006 03:04:05 PM", dateTime, time.UTC); err == nil {
+		// for clients (FileMaker Cloud for AWS)
+		return t, true
+	}
+
+	return time.Time{}, false
+}
+
+// getServerLocation returns the time zone of the server: UTC for Claris FileMaker
+// Cloud, and the time zone of this computer for the local server. The Admin API
+// doesn't report the time zone, so it is unknown (nil) for remote servers.
+func getServerLocation(baseURI string, usingCloud bool) *time.Location {
+	if usingCloud {
+		return time.UTC
+	} else if baseURI == getBaseURI("") {
+		return time.Local
 	}
 
-	return description
+	return nil
 }
 
 func getDateTimeStringOfCurrentTimeZone(dateTime string, outputFormat string, usingCloud bool) string {
@@ -5189,6 +9773,9 @@ Description:
     fmcsadmin HELP OPTIONS
        Lists available options
 
+    fmcsadmin HELP ERROR [CODE...]
+       Displays the description of the error CODE, or lists all error codes
+
 Author: 
     Emic Corporation <https://www.emic.co.jp/>
 `
@@ -5199,27 +9786,34 @@ var commandListHelpTextTemplate = `fmcsadmin commands are:
                     (for FileMaker Server 19.5.1 or later)
     CERTIFICATE     Manage SSL certificates
                     (for FileMaker Server 19.2.1 or later)
+    CHECK           Check the health of the server for monitoring tools
     CLOSE           Close databases
+    COMPLETION      Generate a shell completion script
+    CREDENTIAL      Manage credentials saved in the encrypted credential file
     DELETE          Delete a schedule
-    DISABLE         Disable schedules
+    DISABLE         Disable schedules or plug-ins
     DISCONNECT      Disconnect clients
-    ENABLE          Enable schedules
-    GET             Retrieve server or CWP configuration settings, or retrieve 
-                    the start time of a backup schedule or schedules
+    ENABLE          Enable schedules or plug-ins
+    GET             Retrieve server, connector or CWP configuration settings, or
+                    retrieve the start time of a backup schedule or schedules
     HELP            Get help pages
-    LIST            List clients, databases, plug-ins, or schedules
+    INFO            Show the server version and supported features
+    LINT            Check server settings against a policy file
+    LIST            List backups, clients, databases, plug-ins, or schedules
     OPEN            Open databases
     PAUSE           Temporarily stop database access
     REMOVE          Move databases out of hosted folder
                     (for FileMaker Server 19.3.1 or later)
     RESTART         Restart a server process (for FileMaker Server)
+    RESTORE         Replace hosted databases with copies from a backup
     RESUME          Make paused databases available
     RUN             Run a schedule
     SEND            Send a message
-    SET             Change server or CWP configuration settings, or change the 
-                    start time of a backup schedule
+    SET             Change server, connector or CWP configuration settings, or
+                    change the start time of a backup schedule
+    SHELL           Run commands interactively with a single session
     START           Start a server process (for FileMaker Server)
-    STATUS          Get status of clients or databases
+    STATUS          Get status of clients, databases or the server
     STOP            Stop a server process (for FileMaker Server)
 `
 
@@ -5248,29 +9842,114 @@ Parameters:
     documentation for your shell or command interpreter.
 
 General Options: 
+    --credential-helper CMD    Specify an external command to retrieve
+                               credentials (same protocol as git).
+    --debug                    Log the method, path, status code and latency
+                               of Admin API requests to the standard error,
+                               and print the request and the status code of
+                               the last failed request with an error.
+    --exit-code-mode MODE      Exit with the FileMaker error code truncated by
+                               the OS ("legacy", the default) or with one of
+                               the statuses below ("mapped").
     --fqdn                     Specify the Fully Qualified Domain Name (FQDN)
                                of a remote server via HTTPS.
     -h, --help                 Print this page.
+    --host HOST                Specify the host name of Claris FileMaker Cloud
+                               (e.g. "example" for example.account.filemaker-
+                               cloud.com) and sign in with a Claris ID.
     -i IDENTITYFILE            Specify a private key file for PKI Authentication.
+    --log-file FILE            Append the log of --debug or --trace to FILE.
     -p pass, --password pass   Password to use to authenticate with the server.
+    --password-file FILE       Read the password from the first line of FILE.
+    --password-stdin           Read the password from the standard input.
+    --retries N                Retry GET requests and logins failing with a
+                               connection error, a 5xx status code or the
+                               error 956 or 1701 up to N times with
+                               exponential backoff (default: 0).
+    --retry-max-wait DURATION  Maximum wait between retries (default: 30s).
+    --retry-non-idempotent     Also retry requests that change the server
+                               (e.g. closing databases) with --retries.
+    --trace                    Log Admin API requests like --debug with the
+                               request and response bodies. Passwords, tokens,
+                               encryption keys and private keys are masked.
     -u user, --username user   Username to use to authenticate with the server.
     -v, --version              Print version information.
     -y, --yes                  Automatically answer yes to all command prompts.
 
+    The FMCSADMIN_LOG environment variable enables the log without the options:
+    FMCSADMIN_LOG=debug, FMCSADMIN_LOG=trace or FMCSADMIN_LOG=trace:FILE.
+
+    For Claris FileMaker Cloud, the Claris ID is read from the FMC_USERNAME and
+    FMC_PASSWORD environment variables when -u and -p are not specified, and
+    the refresh token printed by GET REFRESHTOKEN is read from FMC_REFRESH_TOKEN
+    when neither is specified. FMCSADMIN_CLARIS_ID_ENDPOINT,
+    FMCSADMIN_CLARIS_ID_CLIENT_ID and FMCSADMIN_CLARIS_ID_POOL_ID override the
+    Claris ID identity provider. The password is verified with the
+    USER_SRP_AUTH flow of Amazon Cognito and is not sent to the provider.
+
 Options that apply to specific commands:
+    --app-version VERSION      List only clients running the specified
+                               application version.
+    --at time                  Specify the time to send a message.
     -c NUM, --client NUM       Specify a client number to send a message.
+    --cert-critical DAYS       Specify the days before certificate expiry for
+                               a CRITICAL state.
+    --cert-warning DAYS        Specify the days before certificate expiry for
+                               a WARNING state.
+    --clients-critical NUM     Specify the number of clients for a CRITICAL
+                               state.
+    --clients-warning NUM      Specify the number of clients for a WARNING
+                               state.
+    --dry-run                  Show what would be restored without changing
+                               anything.
+    --file NAME                List only clients that opened a database.
     -f, --force                Force database to close or Database Server 
                                to stop, immediately disconnecting clients.
+    --human                    Print sizes, durations and times in a human-
+                               readable format with totals (default on a
+                               terminal, use --human=false to turn it off).
     --intermediateCA IMCAFILE  Specify the file that contains the intermediate
                                CA certificate(s) for certificate import.
+    --ip ADDRESS               List only clients connected from an IP address
+                               or CIDR block.
     --key encryptpass          Specify the database encryption password.
     --keyfile KEYFILE          Specify private key file for certificate import.
     --keyfilepass kfpassword   Specify password needed to read KEYFILE.
+    --maintenance              Skip policy rules that only apply outside
+                               maintenance.
     -m msg, --message msg      Specify a text message to send to clients. 
+    --policy FILE              Specify a policy file to lint the server
+                               configuration against.
     -s, --stats                Return FILE or CLIENT stats.
+    --repeat interval          Specify the interval to send a message again.
     --savekey                  Save the database encryption password.
+    --schedule ID              List only backups of a backup schedule.
+    --sort KEY                 Sort clients by connectTime, duration or user.
     -t sec, --gracetime sec    Specify time in seconds before client is forced
                                to disconnect.
+    --tz ZONE                  Print timestamps in the time zone ZONE (Local,
+                               UTC, Server or an IANA name such as
+                               Asia/Tokyo).
+    --until time               Specify the time to stop sending a message.
+    --user NAME                List only clients with the specified user name.
+    --where CONDITIONS         Select clients to disconnect by conditions.
+
+Exit Status (with --exit-code-mode mapped):
+    0    Success
+    1    Other errors
+    2    Usage error (invalid command, option or parameter)
+    3    Authentication failure
+    4    Database, schedule, client or file not found
+    5    Server unreachable
+    6    Not supported by the server
+    7    Conflict (already exists, already running or too many sessions)
+    8    File permission or read error
+    9    HTTP error response without a FileMaker error code
+    10   Timed out waiting for the server
+    11   Partial failure (the command succeeded for some of the databases)
+    The FileMaker error code is printed as "Error: N (description)". Run
+    "fmcsadmin help error N" for its description. The CHECK and LINT commands
+    use their own exit statuses from 0 to 3.
 `
 
 var cancelHelpTextTemplate = `Usage: fmcsadmin CANCEL [TYPE]
@@ -5318,6 +9997,46 @@ Options:
         issued the certificate.
 `
 
+var checkHelpTextTemplate = `Usage: fmcsadmin CHECK [options] [FILE...]
+
+Description:
+    Checks the health of the server and prints a single line that can be used 
+    by monitoring tools compatible with Nagios plugins such as Icinga. The 
+    following items are checked:
+
+        - FileMaker Admin API responds
+        - The Database Server is running
+        - The specified databases (FILE) are open
+        - No schedule is in an error state
+        - The SSL certificate is not near expiry
+        - The number of clients does not exceed the thresholds
+
+    The exit status is 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN). The 
+    number of open databases, clients and the days before certificate expiry 
+    are printed as performance data after "|".
+
+    Example:
+        FMCSADMIN OK - version 21.1.1.40, database server is RUNNING, 
+        5 files open, 12 clients, certificate expires in 85 days | files=5;;;0 
+        clients=12;100;200;0 cert_days=85;30;7
+
+Options:
+    --clients-warning NUM
+        Returns WARNING when more than NUM clients are connected.
+
+    --clients-critical NUM
+        Returns CRITICAL when more than NUM clients are connected.
+
+    --cert-warning DAYS
+        Returns WARNING when the certificate expires within DAYS days. The 
+        default is 30.
+
+    --cert-critical DAYS
+        Returns CRITICAL when the certificate expires within DAYS days. The 
+        default is 7. Specify 0 for both --cert-warning and --cert-critical 
+        to skip checking the certificate.
+`
+
 var closeHelpTextTemplate = `Usage: fmcsadmin CLOSE [FILE...] [PATH...] [options]
 
 Description:
@@ -5337,6 +10056,69 @@ Options:
         Forces a database to be closed, immediately disconnecting clients.
 `
 
+var completionHelpTextTemplate = `Usage: fmcsadmin COMPLETION [SHELL]
+
+Description:
+    Generates a script that completes commands, subcommands, options and 
+    configuration names for the specified SHELL.
+
+    Valid SHELLs:
+        BASH            source <(fmcsadmin completion bash)
+        ZSH             source <(fmcsadmin completion zsh)
+        FISH            fmcsadmin completion fish > 
+                            ~/.config/fish/completions/fmcsadmin.fish
+        POWERSHELL      fmcsadmin completion powershell | Out-String | 
+                            Invoke-Expression
+
+    When the FMCSADMIN_DYNAMIC_COMPLETION environment variable is set, the 
+    names of hosted databases, schedule IDs and client IDs are also 
+    completed by retrieving them from the server. The credentials are read 
+    from the FMS_USERNAME and FMS_PASSWORD environment variables or the 
+    credential helper without prompting.
+
+Options:
+    No command specific options.
+`
+
+var credentialHelpTextTemplate = `Usage: fmcsadmin CREDENTIAL [CRED_OP] [options]
+
+Description:
+    Manages credentials saved in the encrypted credential file. Saved
+    credentials are used to authenticate with the server specified by the
+    --fqdn option (or the local server) instead of prompting for them.
+
+    Valid credential operations (CRED_OP) are:
+        STORE      Save the username and password for the server. The
+                   credential file is encrypted with a master passphrase.
+        ERASE      Remove the saved credential for the server.
+        LIST       List the servers that have saved credentials.
+
+    Credentials are looked up in the following order:
+        1. -u/-p, --password-file or --password-stdin options
+        2. the external credential helper (--credential-helper or the
+           FMCSADMIN_CREDENTIAL_HELPER environment variable)
+        3. the encrypted credential file
+        4. the FMS_USERNAME and FMS_PASSWORD environment variables
+        5. prompts
+
+    The master passphrase is read from the FMCSADMIN_MASTER_PASSPHRASE
+    environment variable if it is set. The location of the credential file
+    can be changed with the FMCSADMIN_CREDENTIAL_FILE environment variable.
+
+    A credential helper is run with "get", "store" or "erase" appended and
+    exchanges "key=value" lines (protocol, host, username and password) via
+    the standard input and output, in the same way as git credential helpers.
+    For example:
+        fmcsadmin --credential-helper "/usr/local/bin/fms-helper" list files
+
+Options:
+    -u user, --username user
+        Specifies the username to save.
+
+    --password-file FILE, --password-stdin
+        Reads the password to save from FILE or the standard input.
+`
+
 var deleteHelpTextTemplate = `Usage: fmcsadmin DELETE [TYPE] [SCHEDULE_NUMBER]
 
 Description:
@@ -5353,21 +10135,27 @@ Options:
 `
 
 var disableHelpTextTemplate = `Usage: fmcsadmin DISABLE [TYPE] [SCHEDULE_NUMBER]
+       fmcsadmin DISABLE PLUGIN [PLUGIN_ID|NAME...]
 
 Description:
-    Disables a schedule.
+    Disables a schedule or Database Server calculation plug-ins.
 
     Valid TYPEs:
         SCHEDULE        Disables a schedule with schedule ID number
                         SCHEDULE_NUMBER. Use the LIST SCHEDULES
                         command to obtain the ID number of each
                         schedule.
+        PLUGIN          Disables the plug-ins specified by ID or name. Use
+                        the LIST PLUGINS command to obtain the ID 
+                        number and name of each plug-in.
+                        (for FileMaker Server 19.2.1 or later)
 
 Options:
     No command specific options.
 `
 
 var disconnectHelpTextTemplate = `Usage: fmcsadmin DISCONNECT CLIENT [CLIENT_NUMBER] [options]
+       fmcsadmin DISCONNECT CLIENTS --where CONDITIONS [options]
 
 Description: 
     Disconnects the specified client. The CLIENT_NUMBER is the ID number of 
@@ -5375,22 +10163,62 @@ Description:
     their ID numbers. If no CLIENT_NUMBER is specified, all clients are 
     disconnected.
 
+    DISCONNECT CLIENTS disconnects the clients that match all of the 
+    CONDITIONS. The matched clients are listed before they are disconnected.
+
+    Each condition is written as FIELD OPERATOR VALUE, and conditions are
+    joined with "and".
+
+    Valid FIELDs:
+        user            User name
+        computer        Computer name
+        ip              IP address or CIDR block (e.g. 192.168.0.0/24)
+        appversion      Application version (e.g. "Go 21.0.1")
+        extpriv         Extended privilege (e.g. fmapp, fmwebdirect)
+        file            Name of a database opened by the client
+        duration        Connect duration (e.g. 8h, 30m, 01:30:00)
+
+    Valid OPERATORs:
+        =, !=           Equals (wildcards * and ? are allowed) / not equals
+        ~               Contains
+        <, >, <=, >=    Compares (for appversion and duration only)
+
+    Example:
+        fmcsadmin disconnect clients --where "appversion ~ Go and appversion < 21"
+        fmcsadmin disconnect clients --where "file = Sales and duration > 8h"
+
 Options:
     -m message, --message message   
         Specifies a text message to be sent to the client that is being 
         disconnected.
+
+    -t seconds, --gracetime seconds
+        Specifies the total seconds to wait before the client is forcibly
+        disconnected.
+
+    --where CONDITIONS
+        Specifies the conditions to select the clients to be disconnected.
+        (for DISCONNECT CLIENTS)
+
+    -y, --yes
+        Automatically answers yes to all command prompts.
 `
 
 var enableHelpTextTemplate = `Usage: fmcsadmin ENABLE [TYPE] [SCHEDULE_NUMBER]
+       fmcsadmin ENABLE PLUGIN [PLUGIN_ID|NAME...]
 
 Description:
-    Enables a schedule.
+    Enables a schedule or Database Server calculation plug-ins.
 
     Valid TYPEs:
         SCHEDULE        Enables a schedule with schedule ID number
                         SCHEDULE_NUMBER. Use the LIST SCHEDULES
                         command to obtain the ID number of each
                         schedule.
+        PLUGIN          Enables the plug-ins specified by ID or name. Use
+                        the LIST PLUGINS command to obtain the ID 
+                        number and name of each plug-in.
+                        (for FileMaker Server 19.2.1 or later)
 
 Options:
     No command specific options.
@@ -5398,6 +10226,7 @@ Options:
 
 var getHelpTextTemplate = `Usage: fmcsadmin GET BACKUPTIME [ID]
        fmcsadmin GET [CONFIG_TYPE] [NAME1 NAME2 ...]
+       fmcsadmin GET REFRESHTOKEN
 
 
 Description:
@@ -5405,11 +10234,13 @@ Description:
     schedule when you use the optional ID parameter. If you omit the optional ID
     parameter, the start times of all backup schedules are returned.
 
-    The GET CONFIG_TYPE command retrieves the server or Custom Web Publishing 
-    configurations.
+    The GET CONFIG_TYPE command retrieves the server, connector or Custom Web
+    Publishing configurations.
 
     Valid configuration types of CONFIG_TYPE:
       SERVERCONFIG     Retrieve the server configuration settings.            
+      CONNECTORCONFIG  Retrieve the settings of the FileMaker Data API, OData
+                       and FileMaker WebDirect.
       CWPCONFIG        Retrieve the Custom Web Publishing configuration 
                        settings.
 
@@ -5424,6 +10255,16 @@ Description:
                        assigned the Full Access privilege set can be opened for
                        hosting.
 
+    Allowed values of SERVERCONFIG and SERVERPREFS settings:
+%s
+    Valid configuration names of CONNECTORCONFIG:
+      ENABLEDATAAPI    Whether the FileMaker Data API is enabled.
+      ENABLEODATA      Whether OData is enabled (for FileMaker Server 19.1.2 or
+                       later).
+      ENABLEWEBDIRECT  Whether FileMaker WebDirect is enabled.
+    The limits of the FileMaker Data API are not provided by the Admin API 
+    and cannot be retrieved or changed.
+
     Valid configuration names of CWPCONFIG:
       ENABLEPHP        Whether Custom Web Publishing with PHP is enabled.
       ENABLEXML        Whether Custom Web Publishing with XML is enabled.
@@ -5438,15 +10279,84 @@ Description:
     If no configuration name is specified, all supported configurations of the
     corresponding CONFIG_TYPE are listed.
 
+    The GET REFRESHTOKEN command signs in to Claris FileMaker Cloud (--host) with
+    a Claris ID and prints the refresh token. Set the token to the 
+    FMC_REFRESH_TOKEN environment variable to run commands without a password.
+
     Note: Input configuration names are not case sensitive.
 
     Examples:
       fmcsadmin GET BACKUPTIME
+      fmcsadmin GET BACKUPTIME --tz UTC
       fmcsadmin GET BACKUPTIME 2
       fmcsadmin GET SERVERCONFIG HOSTEDFILES SCRIPTSESSIONS
       fmcsadmin GET SERVERCONFIG
+      fmcsadmin GET CONNECTORCONFIG
+      fmcsadmin GET CONNECTORCONFIG ENABLEODATA
       fmcsadmin GET CWPCONFIG ENABLEPHP USEFMPHP
       fmcsadmin GET CWPCONFIG
+
+Options:
+    --tz ZONE
+        Prints the start times of backup schedules in the time zone ZONE 
+        (Local, UTC, Server or an IANA time zone name).
+`
+
+var infoHelpTextTemplate = `Usage: fmcsadmin INFO
+
+Description:
+    Shows the version of the server, the operating system, the status of the
+    Database Server, the number of hosted databases and clients, and which 
+    fmcsadmin commands and settings are supported by the server.
+
+    The Admin API doesn't report the operating system, so it is detected from
+    the folders of the hosted databases, or from the machine running 
+    fmcsadmin when it is connected to the local server. Otherwise "Unknown" 
+    is shown.
+
+Options:
+    No command specific options.
+`
+
+var lintHelpTextTemplate = `Usage: fmcsadmin LINT --policy FILE [options]
+
+Description:
+    Checks the server configuration settings and the hosted databases against 
+    the rules of a policy file written in YAML, prints PASS, FAIL or SKIP for 
+    each rule and returns 1 when any rule fails. A setting the server does 
+    not provide fails the rule.
+
+    Each rule has a SETTING and either EQUALS or MIN and/or MAX. Rules with 
+    OUTSIDE_MAINTENANCE set to true are skipped with the --maintenance option.
+    The settings are those of GET SERVERPREFS and GET CWPCONFIG, and 
+    ENCRYPTED, which is true when all hosted databases are encrypted.
+
+    Example:
+        rules:
+          - name: Secure files only
+            setting: securefilesonly
+            equals: true
+          - setting: authenticatedstream
+            equals: 2
+          - setting: blocknewusersenabled
+            equals: false
+            outside_maintenance: true
+          - setting: enablehttpprotocolnetwork
+            equals: false
+          - setting: enablephp
+            equals: false
+          - setting: cachesize
+            min: 512
+            max: 16384
+          - setting: encrypted
+            equals: true
+
+Options:
+    --policy FILE
+        Specifies the policy file.
+
+    --maintenance
+        Skips the rules that only apply outside maintenance.
 `
 
 var listHelpTextTemplate = `Usage: fmcsadmin LIST [TYPE] [options]
@@ -5455,6 +10365,8 @@ Description:
     Lists items of the specified TYPE. 
 
     Valid TYPEs:
+        BACKUPS         Lists the backup sets in the destination folders of
+                        backup schedules.
         CLIENTS         Lists the connected clients.
         FILES           Lists the hosted databases.
         PLUGINS         List Database Server calculation plug-ins.
@@ -5463,7 +10375,48 @@ Description:
 
 Options:
     -s, --stats
-        Reports additional details for each item.
+        Reports additional details for each item. For CLIENTS, every
+        database opened by each client is listed with its account name and
+        privilege set.
+
+    --file NAME
+        Lists only the clients that have opened the specified database.
+        (for CLIENTS)
+
+    --user NAME
+        Lists only the clients with the specified user name. Wildcards (*, ?)
+        are allowed. (for CLIENTS)
+
+    --app-version VERSION
+        Lists only the clients whose application version contains the
+        specified string (e.g. "FileMaker Go", "21.0"). (for CLIENTS)
+
+    --ip ADDRESS
+        Lists only the clients connected from the specified IP address or
+        CIDR block (e.g. 192.168.0.0/24). (for CLIENTS)
+
+    --sort KEY
+        Sorts the clients by KEY. Valid KEYs are connectTime (oldest first),
+        duration (longest first) and user. (for CLIENTS)
+
+    --schedule ID
+        Lists only the backup sets of the specified backup schedule.
+        (for BACKUPS)
+
+    --human
+        Prints sizes as KB, MB or GB, connection durations as "3h12m" and 
+        the last and next run of schedules as relative times ("in 2h", 
+        "5m ago"), followed by the totals. This is the default when the 
+        output is a terminal. Specify --human=false to print the raw values.
+        (for CLIENTS, FILES and SCHEDULES)
+
+    --tz ZONE
+        Prints timestamps in the time zone ZONE. Valid ZONEs are Local (the 
+        time zone of this computer), UTC, Server (the time zone of the 
+        server) or an IANA time zone name such as America/New_York. The 
+        layout of the timestamps is not changed. The time zone of a remote 
+        server is unknown, so the start times of its schedules are printed 
+        as returned by the server. (for CLIENTS and SCHEDULES)
 `
 
 var openHelpTextTemplate = `Usage: fmcsadmin OPEN [options] [FILE...] [PATH...]
@@ -5528,6 +10481,41 @@ Options: (applicable to SERVER only)
         Specifies a text message to send to the connected clients.
 `
 
+var restoreHelpTextTemplate = `Usage: fmcsadmin RESTORE [options] [BACKUP] [FILE...]
+
+Description:
+    Replaces the hosted databases (FILE) with their copies in the specified 
+    backup set (BACKUP). Each database is closed, moved into the "Removed" 
+    folder, replaced with the copy from the backup set and opened again.
+
+    BACKUP is the name of a backup set listed by the LIST BACKUPS command or 
+    the path of a backup folder. This command must be run on the machine 
+    running FileMaker Server by a user who can write to the database folders, 
+    so it cannot be used with --fqdn.
+
+Options:
+    --dry-run
+        Shows the files that would be closed, removed, copied and opened 
+        without changing anything.
+
+    -f, --force
+        Forces databases to close, immediately disconnecting clients.
+
+    --key encryptpass
+        Specifies the encryption password for the restored database(s). If a 
+        database cannot be opened, fmcsadmin exits with Error: 802.
+
+    -m message, --message message
+        Specifies a text message to send to the clients being disconnected.
+
+    --savekey
+        Saves the encryption password provided with the --key option.
+
+    -y, --yes
+        Automatically answers yes to the confirmation prompt when --force is 
+        also specified. Without --force, the prompt is shown even with -y.
+`
+
 var resumeHelpTextTemplate = `Usage: fmcsadmin RESUME [FILE...] [PATH...]
 
 Description:
@@ -5563,23 +10551,70 @@ Description:
     For example: 
         fmcsadmin SEND -c 2 -m "This is a test message"
 
+    With the --at, --repeat or --until option, fmcsadmin runs in the 
+    foreground until the last message is sent. The recipients are looked 
+    up again for each delivery. In the message, {remaining} is replaced 
+    with the time remaining until the --until time and {until} with the 
+    --until time itself.
+    For example: 
+        fmcsadmin SEND --at "2026-10-20 17:30" --repeat 5m 
+            --until "2026-10-20 18:00" -m "Server closes in {remaining}" 
+            Sales.fmp12
+
 Options:
     -m message, --message message
         Specifies the text message to send.
 
     -c, --client
         Specifies a CLIENT_NUMBER.
+
+    --at time
+        Specifies the time to send the message, for example 
+        "2026-10-20 17:30", "17:30" or "+30m".
+
+    --repeat interval
+        Sends the message repeatedly at the specified interval (1m or 
+        longer, for example 5m or 1h). Requires the --until option.
+
+    --until time
+        Specifies the time to stop sending the message. The format is the 
+        same as the --at option.
+`
+
+var shellHelpTextTemplate = `Usage: fmcsadmin SHELL [options]
+
+Description:
+    Logs in to the server once and starts an interactive shell that accepts 
+    the same commands as fmcsadmin (LIST, OPEN, CLOSE, STATUS, SET, ...) 
+    without the leading "fmcsadmin". For example:
+        fmcsadmin> list files -s
+        fmcsadmin> close -y Sales
+
+    Every command uses the same session. When a command is rejected because 
+    the session has expired, the shell logs in again with the same 
+    credentials and runs the command again. Type EXIT or QUIT, or press 
+    Ctrl-D to log out and leave the shell. The exit status of the shell is 
+    the last non-zero exit status of the commands.
+
+    On a terminal, the up and down arrow keys recall the command history and 
+    the Tab key completes commands, database names and client IDs retrieved 
+    from the server.
+
+Options:
+    No command specific options.
 `
 
 var setHelpTextTemplate = `Usage: fmcsadmin SET [CONFIG_TYPE] [NAME1=VALUE1 NAME2=VALUE2 ...]
 
 
 Description:
-    The SET CONFIG_TYPE command changes the server or Custom Web Publishing
-    configuration settings.
+    The SET CONFIG_TYPE command changes the server, connector or Custom Web
+    Publishing configuration settings.
 
     Valid configuration types of CONFIG_TYPE:
       SERVERCONFIG     Change the server configuration settings.             
+      CONNECTORCONFIG  Enable or disable the FileMaker Data API, OData and
+                       FileMaker WebDirect.
       CWPCONFIG        Change the Custom Web Publishing configuration 
                        settings.
 
@@ -5594,6 +10629,16 @@ Description:
                        assigned the Full Access privilege set can be opened for
                        hosting. 
 
+    Allowed values of SERVERCONFIG and SERVERPREFS settings:
+%s
+    Valid configuration names of CONNECTORCONFIG:
+      ENABLEDATAAPI    Whether the FileMaker Data API is enabled.
+      ENABLEODATA      Whether OData is enabled (for FileMaker Server 19.1.2 or
+                       later).
+      ENABLEWEBDIRECT  Whether FileMaker WebDirect is enabled.
+    The limits of the FileMaker Data API are not provided by the Admin API 
+    and cannot be retrieved or changed.
+
     Valid configuration names of CWPCONFIG:
       ENABLEPHP        Whether Custom Web Publishing with PHP is enabled.
       ENABLEXML        Whether Custom Web Publishing with XML is enabled.
@@ -5607,9 +10652,13 @@ Description:
 
     Note: Input configuration names are not case sensitive.
 
+    If changing one of the settings fails, the settings already changed by the
+    command are restored to their previous values.
+
     Examples:
       fmcsadmin SET SERVERCONFIG CACHESIZE=1024 SECUREFILESONLY=true
       fmcsadmin SET CWPCONFIG ENABLEPHP=true ENCODING=ISO-8859-1 LOCALE=de
+      fmcsadmin SET CONNECTORCONFIG ENABLEODATA=false ENABLEWEBDIRECT=true
 `
 
 var startHelpTextTemplate = `Usage: fmcsadmin START [TYPE]
@@ -5633,6 +10682,12 @@ Description:
         CLIENT          Retrieves the status of a client specified by 
                         CLIENT_NUMBER.
         FILE            Retrieves the status of database(s) specified by FILE.
+        SERVER          Retrieves a summary of the server: the state of the
+                        Database Server, the Admin API, the number of hosted,
+                        open and paused files, connected clients by type,
+                        running schedules and whether new users are blocked.
+
+    If TYPE is not specified, SERVER is used.
 
 Options:
     No command specific options.